@@ -0,0 +1,245 @@
+// Package config reads the server's settings from the environment, or
+// failing that from CONFIG_FILE, a YAML (.yaml, .yml) or TOML (.toml) file
+// of the same names, e.g.
+//
+//	CACHE_TTL_SECONDS: 600
+//	prewarm_languages: [tamil, hindi]
+//
+// Keys are case-insensitive and lists are joined with commas. Values are
+// read through Get, so the file works for knobs read during package
+// initialization too.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Kind is how a setting's value is parsed.
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Float
+	Bool
+)
+
+// Setting is one knob: its type, default and any further check.
+type Setting struct {
+	Name    string
+	Kind    Kind
+	Default string // as shown; empty when it has none or depends on another setting
+	Secret  bool
+	Check   func(string) error
+}
+
+// fileValues is CONFIG_FILE's settings, read on first use. The path itself
+// only comes from the environment.
+var fileValues = sync.OnceValues(func() (map[string]string, error) {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CONFIG_FILE: %w", err)
+	}
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("CONFIG_FILE %s: want a .yaml, .yml or .toml file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing CONFIG_FILE %s: %w", path, err)
+	}
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		s, err := flatten(value)
+		if err != nil {
+			return nil, fmt.Errorf("CONFIG_FILE %s: %s: %w", path, key, err)
+		}
+		values[strings.ToUpper(key)] = s
+	}
+	return values, nil
+})
+
+// flatten turns a file value into what the environment would hold.
+func flatten(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			s, err := flatten(item)
+			if err != nil || strings.Contains(s, ",") {
+				return "", errors.New("lists may only hold plain values")
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	case map[string]any:
+		return "", errors.New("nested tables aren't supported")
+	}
+	return fmt.Sprint(value), nil
+}
+
+// File returns the path of the loaded CONFIG_FILE, or "" without one.
+func File() string {
+	return os.Getenv("CONFIG_FILE")
+}
+
+// Get returns key's value from the environment, or from CONFIG_FILE when
+// the environment leaves it unset. A file that fails to load is reported by
+// Validate; until then it is treated as empty.
+func Get(key string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	values, _ := fileValues()
+	return values[key]
+}
+
+// Source says where Get(key) came from: "env", "file" or "default".
+func Source(key string) string {
+	if os.Getenv(key) != "" {
+		return "env"
+	}
+	if values, _ := fileValues(); values[key] != "" {
+		return "file"
+	}
+	return "default"
+}
+
+// Validate checks CONFIG_FILE and every one of settings that is set, and
+// reports all the problems at once so a bad deploy needs one fix, not many.
+func Validate(settings []Setting) error {
+	values, err := fileValues()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	var errs []error
+	for key := range values {
+		if !slices.ContainsFunc(settings, func(s Setting) bool { return s.Name == key }) {
+			errs = append(errs, fmt.Errorf("config: CONFIG_FILE: unknown setting %s", key))
+		}
+	}
+	for _, s := range settings {
+		raw := Get(s.Name)
+		if raw == "" {
+			continue
+		}
+		if err := s.Validate(raw); err != nil {
+			errs = append(errs, fmt.Errorf("config: invalid %s=%q (from %s): %w", s.Name, s.Display(raw), Source(s.Name), err))
+		}
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+// Validate checks raw against the setting's kind and its own check.
+func (s Setting) Validate(raw string) error {
+	var err error
+	switch s.Kind {
+	case Int:
+		_, err = strconv.Atoi(raw)
+		if err != nil {
+			err = errors.New("not an integer")
+		}
+	case Float:
+		_, err = strconv.ParseFloat(raw, 64)
+		if err != nil {
+			err = errors.New("not a number")
+		}
+	case Bool:
+		_, err = strconv.ParseBool(raw)
+		if err != nil {
+			err = errors.New("not true or false")
+		}
+	}
+	if err == nil && s.Check != nil {
+		err = s.Check(raw)
+	}
+	return err
+}
+
+// Display is raw as the config route and error messages may show it.
+func (s Setting) Display(raw string) string {
+	if s.Secret && raw != "" {
+		return "[redacted]"
+	}
+	return raw
+}
+
+// Value is one setting as the server sees it.
+type Value struct {
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"` // Secrets show as [redacted]
+	Source string `json:"source"`          // env, file or default
+}
+
+// Effective returns the value every one of settings takes, secrets redacted.
+func Effective(settings []Setting) []Value {
+	values := make([]Value, 0, len(settings))
+	for _, s := range settings {
+		value := s.Default
+		if raw := Get(s.Name); raw != "" {
+			value = s.Display(raw)
+		}
+		values = append(values, Value{Name: s.Name, Value: value, Source: Source(s.Name)})
+	}
+	return values
+}
+
+func AtLeast(lo int) func(string) error {
+	return func(raw string) error {
+		if v, _ := strconv.Atoi(raw); v < lo {
+			return fmt.Errorf("must be at least %d", lo)
+		}
+		return nil
+	}
+}
+
+func Between(lo, hi int) func(string) error {
+	return func(raw string) error {
+		if v, _ := strconv.Atoi(raw); v < lo || v > hi {
+			return fmt.Errorf("must be between %d and %d", lo, hi)
+		}
+		return nil
+	}
+}
+
+func OneOf(allowed ...string) func(string) error {
+	return func(raw string) error {
+		if !slices.Contains(allowed, strings.ToLower(strings.TrimSpace(raw))) {
+			return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+		}
+		return nil
+	}
+}
+
+// URLs accepts a comma-separated list of absolute http(s) URLs.
+func URLs(raw string) error {
+	for _, part := range strings.Split(raw, ",") {
+		u, err := url.Parse(strings.TrimSpace(part))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("want absolute http(s) URLs")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSettings = []Setting{
+	{Name: "PORT", Default: "8080", Kind: Int, Check: Between(1, 65535)},
+	{Name: "LOG_LEVEL", Default: "info", Check: OneOf("debug", "info")},
+	{Name: "API_KEYS", Secret: true},
+}
+
+// The file is read once per process, so everything that depends on it is
+// checked in this one test.
+func TestFileAndEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 99999\nlog_level: info\napi_keys: [k1, k2]\nbogus: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("LOG_LEVEL", "debug")
+
+	if got := Get("LOG_LEVEL"); got != "debug" || Source("LOG_LEVEL") != "env" {
+		t.Errorf("LOG_LEVEL = %q from %s, want debug from env", got, Source("LOG_LEVEL"))
+	}
+	if got := Get("API_KEYS"); got != "k1,k2" || Source("API_KEYS") != "file" {
+		t.Errorf("API_KEYS = %q from %s, want k1,k2 from file", got, Source("API_KEYS"))
+	}
+
+	err := Validate(testSettings)
+	if err == nil {
+		t.Fatal("Validate accepted an out-of-range port and an unknown key")
+	}
+	for _, want := range []string{"unknown setting BOGUS", `invalid PORT="99999" (from file): must be between 1 and 65535`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate error %q doesn't mention %q", err, want)
+		}
+	}
+
+	for _, v := range Effective(testSettings) {
+		if v.Name == "API_KEYS" && v.Value != "[redacted]" {
+			t.Errorf("API_KEYS shown as %q, want it redacted", v.Value)
+		}
+	}
+}
+
+func TestSettingValidate(t *testing.T) {
+	tests := []struct {
+		setting Setting
+		raw     string
+		wantErr bool
+	}{
+		{Setting{Kind: Int}, "12", false},
+		{Setting{Kind: Int}, "twelve", true},
+		{Setting{Kind: Float}, "0.5", false},
+		{Setting{Kind: Bool}, "maybe", true},
+		{Setting{Kind: Int, Check: AtLeast(1)}, "0", true},
+		{Setting{Check: URLs}, "https://a.example, http://b.example", false},
+		{Setting{Check: URLs}, "a.example", true},
+	}
+	for _, tt := range tests {
+		if err := tt.setting.Validate(tt.raw); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) = %v, want error %v", tt.raw, err, tt.wantErr)
+		}
+	}
+}
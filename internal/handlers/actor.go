@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const defaultActorNameTTLHours = 24
+
+// unknownActorName is reported when neither the results page nor the
+// profile page names the actor.
+const unknownActorName = "Unknown Actor"
+
+// actorNames maps language/actor code to the actor's name. Names don't
+// change, so entries live for ACTOR_NAME_TTL_HOURS and every page of a
+// filmography after the first is served without another lookup.
+var actorNames = newTTLCache[string](time.Duration(EnvInt("ACTOR_NAME_TTL_HOURS", defaultActorNameTTLHours)) * time.Hour)
+
+// resolveActorName returns the actor's name for a cast listing. The results
+// heading is used when present; otherwise the cached name, and failing that
+// the actor's profile page. A failed profile lookup is not an error, since
+// the listing itself is still good.
+func resolveActorName(ctx context.Context, language, actorCode, heading string) string {
+	key := language + "/" + actorCode
+	if heading != "" {
+		actorNames.set(key, heading)
+		return heading
+	}
+	if name, ok := actorNames.get(key); ok {
+		return name
+	}
+	name, err := scrapeActorProfile(ctx, language, actorCode)
+	if err != nil || name == "" {
+		return unknownActorName
+	}
+	actorNames.set(key, name)
+	return name
+}
+
+// scrapeActorProfile reads the name from an actor's profile page.
+func scrapeActorProfile(ctx context.Context, language, actorCode string) (string, error) {
+	profileUrl := fmt.Sprintf("%s/movie/cast/%s/?lang=%s", scraper.Einthusan.BaseURL(), url.PathEscape(actorCode), language)
+	res, err := fetchUpstream(ctx, profileUrl)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if err := checkBlocked(res, doc); err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", scraper.UnexpectedStatus("profile page", res)
+	}
+	return scraper.FirstText(doc.Selection, scraper.Selectors.ActorName), nil
+}
+
+// actorUrl is the first page of an actor's Einthusan filmography.
+func actorUrl(language, actorCode string) string {
+	return fmt.Sprintf("%s/movie/results/?find=Cast&id=%s&lang=%s&role=", scraper.Einthusan.BaseURL(), actorCode, language)
+}
+
+// actorFilmography lists the movies an actor appears in.
+func actorFilmography(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if resp, ok := actorListing(c, language, c.Param("actorcode"), page); ok {
+		respond(c, http.StatusOK, resp)
+	}
+}
+
+// actorListing loads actorCode's filmography from page, honouring
+// page_size, pages and fields. It responds itself when it fails.
+func actorListing(c *gin.Context, language, actorCode string, page int) (models.ActorResponse, bool) {
+	source, ok := requireProvider(c, language)
+	if !ok {
+		return models.ActorResponse{}, false
+	}
+	if isKnownMissing("actor", language+"/"+actorCode) {
+		respondError(c, http.StatusNotFound, "actor_not_found", "actor not found")
+		return models.ActorResponse{}, false
+	}
+	pageSize, ok := parsePageSize(c)
+	if !ok {
+		return models.ActorResponse{}, false
+	}
+	page, pageCount, ok := parsePageSpan(c, page)
+	if !ok {
+		return models.ActorResponse{}, false
+	}
+	full, ok := wantsFullFields(c)
+	if !ok {
+		return models.ActorResponse{}, false
+	}
+	fetch := func(ctx context.Context, page int) (scraper.Listing, error) {
+		return source.byActor(ctx, language, actorCode, page)
+	}
+	var pages pageRange
+	var err error
+	if pageCount > 0 {
+		pages, err = fetchPageSpan(c.Request.Context(), fetch, page, pageCount)
+	} else {
+		pages, err = fetchPages(c.Request.Context(), fetch, page, pageSize)
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return models.ActorResponse{}, false
+	}
+	// An unknown code still renders a results page, just with no movies and no name.
+	if len(pages.Movies) == 0 && pages.Heading == "" && !pages.Degraded {
+		markMissing("actor", language+"/"+actorCode)
+		respondError(c, http.StatusNotFound, "actor_not_found", "actor not found")
+		return models.ActorResponse{}, false
+	}
+	if !full {
+		basicFields(pages.Movies)
+	}
+	actorName := resolveActorName(c.Request.Context(), language, actorCode, pages.Heading)
+	return models.ActorResponse{ActorID: actorCode, ActorName: actorName, HasMore: pages.HasMore, Language: language, Movies: pages.Movies, NextPage: pages.nextPage(), Page: page, PageSize: scraper.PageSize(), Count: len(pages.Movies), Reason: listingReason(pages.Movies, pages.Degraded, reasonUpstreamEmpty), TotalPages: pages.TotalPages, TotalResults: pages.TotalResults, ScrapeDegraded: pages.Degraded}, true
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin guards the /admin routes with the ADMIN_API_KEY bearer token.
+// The routes are disabled entirely when no key is configured.
+func requireAdmin() gin.HandlerFunc {
+	key := Setting("ADMIN_API_KEY")
+	return func(c *gin.Context) {
+		if key == "" {
+			respondError(c, http.StatusForbidden, "admin_disabled", "admin API is disabled")
+			return
+		}
+		token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
+			respondError(c, http.StatusUnauthorized, "unauthorized", "invalid admin key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// flushCache evicts cached listings, optionally only those for ?language= or
+// whose upstream URL starts with ?prefix=. An unscoped flush also clears the
+// filter, availability and actor name caches.
+func flushCache(c *gin.Context) {
+	language := strings.TrimSpace(c.Query("language"))
+	prefix := c.Query("prefix")
+	evicted := cache.flush(language, prefix)
+	if language == "" && prefix == "" {
+		evicted += filtersCache.clear() + availabilityCache.clear() + actorNames.clear()
+	}
+	c.JSON(http.StatusOK, gin.H{"evicted": evicted})
+}
+
+// listCache answers /admin/cache with the cached listings, optionally only
+// those for ?language= or whose upstream URL starts with ?prefix=.
+func listCache(c *gin.Context) {
+	entries := cache.inspect(strings.TrimSpace(c.Query("language")), c.Query("prefix"))
+	bytes := 0
+	for _, entry := range entries {
+		bytes += entry.Bytes
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries), "bytes": bytes})
+}
+
+// evictCacheEntry answers DELETE /admin/cache?url= by dropping the listing
+// cached for exactly that upstream URL.
+func evictCacheEntry(c *gin.Context) {
+	url := c.Query("url")
+	if url == "" {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "url is required")
+		return
+	}
+	if !cache.evict(url) {
+		respondError(c, http.StatusNotFound, "not_cached", "no cached listing for that url")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"evicted": 1})
+}
+
+// showBreaker answers /admin/breaker with the circuit breaker's state.
+func showBreaker(c *gin.Context) {
+	c.JSON(http.StatusOK, breaker.status())
+}
+
+// listErrors answers /admin/errors with the latest upstream failures and the
+// URLs they were for, newest first; ?limit= keeps only the first few.
+func listErrors(c *gin.Context) {
+	errs := recentErrors.list()
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "invalid_parameter", "limit must be a positive integer")
+			return
+		}
+		errs = errs[:min(limit, len(errs))]
+	}
+	c.JSON(http.StatusOK, gin.H{"errors": errs})
+}
+
+// triggerPrewarm answers POST /admin/prewarm by starting a refresh round in
+// the background: the languages= list (PREWARM_LANGUAGES, or every language,
+// by default), pages= pages of each. It answers 409 while a round, scheduled
+// or not, is still running.
+func triggerPrewarm(c *gin.Context) {
+	languages := watchedLanguages
+	if c.Query("languages") != "" || len(languages) == 0 {
+		var ok bool
+		if languages, ok = languageList(c, "languages"); !ok {
+			return
+		}
+	}
+	pages := defaultPrewarmPages
+	if raw := c.Query("pages"); raw != "" {
+		var err error
+		pages, err = strconv.Atoi(raw)
+		if err != nil || pages < 1 || pages > maxFillPages {
+			respondError(c, http.StatusBadRequest, "invalid_parameter", "pages must be between 1 and "+strconv.Itoa(maxFillPages))
+			return
+		}
+	}
+	if !prewarmRunning.CompareAndSwap(false, true) {
+		respondError(c, http.StatusConflict, "prewarm_running", "a prewarm round is already running")
+		return
+	}
+	go func() {
+		defer prewarmRunning.Store(false)
+		prewarm(context.Background(), languages, pages)
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"languages": languages, "pages": pages})
+}
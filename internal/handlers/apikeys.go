@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultAPIKeyDailyQuota = 1000
+
+// apiKeys holds the keys clients must present in X-API-Key. It stays empty,
+// and authentication off, unless API_KEYS or API_KEYS_FILE configures some.
+var apiKeys = &keyRegistry{keys: map[string]*apiKey{}}
+
+type keyRegistry struct {
+	mu   sync.Mutex
+	keys map[string]*apiKey
+}
+
+// apiKey is one configured key and its usage today. A quota of 0 or less
+// means unlimited.
+type apiKey struct {
+	quota int
+	used  int
+	day   string // UTC date that used counts for
+}
+
+type UsageResponse struct {
+	Key       string    `json:"key"` // masked
+	Quota     int       `json:"quota"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"` // -1 when unlimited
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// LoadAPIKeys reads keys from API_KEYS (comma-separated) and API_KEYS_FILE
+// (one per line, # for comments). Each entry is key or key:quota; without a
+// quota, API_KEY_DAILY_QUOTA applies.
+func LoadAPIKeys(list, path string) error {
+	defaultQuota := EnvInt("API_KEY_DAILY_QUOTA", defaultAPIKeyDailyQuota)
+	entries := strings.Split(list, ",")
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("reading API_KEYS_FILE: %w", err)
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			entries = append(entries, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading API_KEYS_FILE: %w", err)
+		}
+	}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, rawQuota, hasQuota := strings.Cut(entry, ":")
+		quota := defaultQuota
+		if hasQuota {
+			var err error
+			if quota, err = strconv.Atoi(strings.TrimSpace(rawQuota)); err != nil {
+				return fmt.Errorf("invalid quota for API key %s: %q", maskKey(key), rawQuota)
+			}
+		}
+		apiKeys.keys[strings.TrimSpace(key)] = &apiKey{quota: quota}
+	}
+	return nil
+}
+
+func (r *keyRegistry) enabled() bool {
+	return len(r.keys) > 0
+}
+
+// use spends one request of key's daily quota. It reports false when the key
+// is unknown, and a positive wait when today's quota is used up.
+func (r *keyRegistry) use(key string, spend bool) (UsageResponse, time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[key]
+	if !ok {
+		return UsageResponse{}, 0, false
+	}
+	now := time.Now().UTC()
+	if today := now.Format(time.DateOnly); k.day != today {
+		k.day, k.used = today, 0
+	}
+	resets := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if spend && k.quota > 0 && k.used >= k.quota {
+		return UsageResponse{}, resets.Sub(now), true
+	}
+	if spend {
+		k.used++
+	}
+	usage := UsageResponse{Key: maskKey(key), Quota: k.quota, Used: k.used, Remaining: -1, ResetsAt: resets}
+	if k.quota > 0 {
+		usage.Remaining = k.quota - k.used
+	}
+	return usage, 0, true
+}
+
+// maskKey keeps only the last four characters of a key, for responses and logs.
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
+
+// requireAPIKey checks X-API-Key and spends a request of its daily quota. It
+// does nothing when no keys are configured.
+func requireAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !apiKeys.enabled() {
+			c.Next()
+			return
+		}
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			respondError(c, http.StatusUnauthorized, "api_key_required", "X-API-Key header is required")
+			return
+		}
+		usage, wait, ok := apiKeys.use(key, c.FullPath() != "/usage")
+		if !ok {
+			respondError(c, http.StatusUnauthorized, "invalid_api_key", "invalid API key")
+			return
+		}
+		if wait > 0 {
+			secs := int(math.Ceil(wait.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(secs))
+			body := errorBody("quota_exceeded", "daily quota exceeded")
+			body["retry_after"] = secs
+			abortWithError(c, http.StatusTooManyRequests, body)
+			return
+		}
+		c.Set("usage", usage)
+		c.Next()
+	}
+}
+
+// showUsage reports the calling key's quota for today without spending any of it.
+func showUsage(c *gin.Context) {
+	usage, ok := c.Get("usage")
+	if !ok {
+		respondError(c, http.StatusNotFound, "auth_disabled", "API keys are not enabled")
+		return
+	}
+	respond(c, http.StatusOK, usage)
+}
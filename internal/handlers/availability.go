@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+type AvailabilityResponse struct {
+	ID        string `json:"id"`
+	Language  string `json:"language"`
+	Available bool   `json:"available"`
+}
+
+const availabilityTTL = 2 * time.Minute
+
+var availabilityCache = newTTLCache[bool](availabilityTTL)
+
+// CheckAvailability reports whether a movie's watch page loads and carries a
+// player token, without going through the full stream extraction. Only a
+// verdict read from the watch page itself is cached: a block page or an
+// upstream error is returned as one, so an outage doesn't gray out every
+// movie for availabilityTTL.
+func checkAvailability(ctx context.Context, language, id string) (bool, error) {
+	key := language + "/" + id
+	if available, ok := availabilityCache.get(key); ok {
+		return available, nil
+	}
+	if isKnownMissing("movie", key) {
+		return false, scraper.ErrNotFound
+	}
+	res, err := fetchUpstream(ctx, watchUrl(language, id))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		markMissing("movie", key)
+		return false, scraper.ErrNotFound
+	}
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return false, err
+	}
+	if err := checkBlocked(res, doc); err != nil {
+		return false, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return false, scraper.UnexpectedStatus("upstream", res)
+	}
+	if doc.Find(scraper.Selectors.Container).Length() == 0 {
+		markMissing("movie", key)
+		return false, scraper.ErrNotFound
+	}
+
+	token, _ := doc.Find("#UIVideoPlayer").Attr("data-ejpingables")
+	available := strings.TrimSpace(token) != ""
+	availabilityCache.set(key, available)
+	return available, nil
+}
+
+// movieAvailability reports whether a movie can currently be played.
+func movieAvailability(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	available, err := upstream.CheckAvailability(c.Request.Context(), language, id)
+	if errors.Is(err, scraper.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+		return
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	respond(c, http.StatusOK, AvailabilityResponse{ID: id, Language: language, Available: available})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"apppp/internal/scraper"
+)
+
+const watchPage = `<div id="UIMovieSummary"><ul><li><div class="block2"><a class="title"><h3>Theri</h3></a></div></li></ul></div>`
+
+func TestCheckAvailability(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		want      bool
+		wantErr   error
+		cacheable bool
+	}{
+		{"playable", http.StatusOK, watchPage + `<div id="UIVideoPlayer" data-ejpingables="tok"></div>`, true, nil, true},
+		{"no token", http.StatusOK, watchPage + `<div id="UIVideoPlayer"></div>`, false, nil, true},
+		{"server error", http.StatusServiceUnavailable, "down", false, scraper.ErrUnexpectedPage, false},
+		{"challenge", http.StatusOK, `<html><title>Just a moment...</title></html>`, false, scraper.ErrUpstreamBlocked, false},
+		{"ban page", http.StatusForbidden, `<html><h1>Access denied</h1><p>Error 1020</p></html>`, false, scraper.ErrUpstreamBlocked, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			for range 2 {
+				got, err := checkAvailability(t.Context(), "tamil", "abc")
+				if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("available = %v, want %v", got, tt.want)
+				}
+			}
+			want := int64(2)
+			if tt.cacheable {
+				want = 1
+			}
+			if n := hits.Load(); n != want {
+				t.Errorf("upstream hit %d times, want %d", n, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const (
+	// maxBatchSize bounds how many movies one batch request may ask for.
+	maxBatchSize = 50
+	// maxConcurrentBatch bounds the detail pages one batch fetches at once.
+	maxConcurrentBatch = 4
+)
+
+// BatchItem is one requested movie: its details, or why they couldn't be read.
+type BatchItem struct {
+	Input string              `json:"input"` // The page URL or ID as posted
+	Movie *models.MovieDetail `json:"movie,omitempty"`
+	Error *BatchError         `json:"error,omitempty"`
+}
+
+// BatchError is a failed item, with the status and code the single-movie
+// endpoint would have answered with.
+type BatchError struct {
+	Status         int    `json:"status"`
+	Code           string `json:"code"`
+	Message        string `json:"message"`
+	UpstreamStatus int    `json:"upstream_status,omitempty"`
+}
+
+type BatchResponse struct {
+	Results   []BatchItem `json:"results"` // In the order posted
+	Succeeded int         `json:"succeeded"`
+	Failed    int         `json:"failed"`
+}
+
+// batchMovies answers POST /movies/batch, whose body is a JSON array of
+// watch page URLs or movie IDs. IDs are looked up in ?language=; page URLs
+// carry their own lang parameter, falling back to ?language= without one.
+// provider= picks the source; an item in a language it doesn't serve fails.
+// Items are fetched a few at a time and fail independently, so the response
+// is 200 whenever the body itself is valid.
+func batchMovies(c *gin.Context) {
+	var inputs []string
+	if err := c.ShouldBindJSON(&inputs); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_body", "body must be a JSON array of page URLs or movie IDs")
+		return
+	}
+	if len(inputs) == 0 || len(inputs) > maxBatchSize {
+		respondError(c, http.StatusUnprocessableEntity, "invalid_body", fmt.Sprintf("body must list between 1 and %d movies", maxBatchSize))
+		return
+	}
+	fallback := ""
+	if raw := c.Query("language"); raw != "" {
+		var ok bool
+		if fallback, ok = checkLanguage(c, raw); !ok {
+			return
+		}
+	}
+
+	source, ok := lookupProvider(c)
+	if !ok {
+		return
+	}
+	served := source.info().Languages
+	resp := BatchResponse{Results: make([]BatchItem, len(inputs))}
+	sem := make(chan struct{}, maxConcurrentBatch)
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		resp.Results[i].Input = input
+		language, id, err := movieTarget(input, fallback)
+		if err != nil {
+			resp.Results[i].Error = &BatchError{Status: http.StatusBadRequest, Code: "invalid_item", Message: err.Error()}
+			continue
+		}
+		if !slices.Contains(served, language) {
+			resp.Results[i].Error = &BatchError{Status: http.StatusBadRequest, Code: "unsupported_language", Message: fmt.Sprintf("%s does not serve %s", source.info().Name, language)}
+			continue
+		}
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			detail, err := source.details(c.Request.Context(), language, id)
+			switch {
+			case errors.Is(err, scraper.ErrNotFound):
+				resp.Results[i].Error = &BatchError{Status: http.StatusNotFound, Code: "movie_not_found", Message: "movie not found"}
+			case err != nil:
+				status, code, message := classifyScrapeError(err)
+				resp.Results[i].Error = &BatchError{Status: status, Code: code, Message: message, UpstreamStatus: upstreamStatus(err)}
+			default:
+				resp.Results[i].Movie = detail
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, item := range resp.Results {
+		if item.Error != nil {
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+	}
+	respond(c, http.StatusOK, resp)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerFailures        = 5
+	defaultBreakerCooldownSeconds = 30
+)
+
+// circuitBreaker stops scrapes from waiting on an Einthusan that is down.
+// After BREAKER_FAILURES consecutive failed fetches it opens, and fetches
+// fail at once for BREAKER_COOLDOWN_SECONDS. Then one fetch is let through
+// as a probe: success closes the breaker, failure opens it for another
+// cool-down. BREAKER_FAILURES=0 disables it.
+//
+// A failure is an error, a 5xx or a Cloudflare challenge after every mirror
+// was tried; a 404 is a healthy answer. 429s are left to upstreamBackoff.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time // zero while closed
+	probing   bool
+}
+
+var breaker = newCircuitBreaker()
+
+func newCircuitBreaker() *circuitBreaker {
+	cooldown := EnvInt("BREAKER_COOLDOWN_SECONDS", defaultBreakerCooldownSeconds)
+	if cooldown <= 0 {
+		log.Printf("config: ignoring BREAKER_COOLDOWN_SECONDS, must be positive")
+		cooldown = defaultBreakerCooldownSeconds
+	}
+	return &circuitBreaker{threshold: EnvInt("BREAKER_FAILURES", defaultBreakerFailures), cooldown: time.Duration(cooldown) * time.Second}
+}
+
+// allow reports whether a fetch may go ahead. Once the cool-down is over the
+// first caller becomes the probe, and the rest keep failing fast until
+// record or release says how it went.
+func (b *circuitBreaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch {
+	case b.openUntil.IsZero():
+		return nil
+	case now.Before(b.openUntil):
+		return &backoffError{until: b.openUntil, circuit: true}
+	case b.probing:
+		return &backoffError{until: now.Add(time.Second), circuit: true}
+	}
+	b.probing = true
+	return nil
+}
+
+// record counts the outcome of a fetch allow let through.
+func (b *circuitBreaker) record(ok bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	probe := b.probing
+	b.probing = false
+	if ok {
+		if !b.openUntil.IsZero() {
+			log.Printf("breaker: upstream recovered, closing")
+		}
+		b.failures, b.openUntil = 0, time.Time{}
+		return
+	}
+	b.failures++
+	if probe || b.failures >= b.threshold {
+		if b.openUntil.IsZero() {
+			log.Printf("breaker: opening after %d consecutive failures", b.failures)
+		}
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// release gives back the probe of a fetch that ended without telling us
+// anything, such as one the client cancelled.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// BreakerStatus is the breaker as /admin/breaker shows it.
+type BreakerStatus struct {
+	State           string     `json:"state"`
+	Enabled         bool       `json:"enabled"`
+	Failures        int        `json:"failures"`  // Consecutive failed fetches
+	Threshold       int        `json:"threshold"` // Failures that open it
+	CooldownSeconds int        `json:"cooldown_seconds"`
+	OpenUntil       *time.Time `json:"open_until,omitempty"`
+	Probing         bool       `json:"probing"` // A half-open probe fetch is in flight
+}
+
+func (b *circuitBreaker) status() BreakerStatus {
+	state := b.state()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BreakerStatus{State: state, Enabled: b.threshold > 0, Failures: b.failures, Threshold: b.threshold, CooldownSeconds: int(b.cooldown / time.Second), Probing: b.probing}
+	if !b.openUntil.IsZero() {
+		until := b.openUntil
+		status.OpenUntil = &until
+	}
+	return status
+}
+
+// state is "closed", "open" or "half_open", for /metrics.
+func (b *circuitBreaker) state() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openUntil.IsZero():
+		return "closed"
+	case time.Now().Before(b.openUntil):
+		return "open"
+	}
+	return "half_open"
+}
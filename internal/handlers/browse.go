@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// browse lists a language's recent or popular movies. It falls back to
+// DEFAULT_LANGUAGE when the language is omitted. window= picks the period
+// popular counts views over, all time by default.
+func browse(c *gin.Context) {
+	language, ok := browseLanguage(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	category := strings.ToLower(c.DefaultQuery("category", "recent"))
+	if resp, ok := browseListing(c, language, category, strings.ToLower(c.Query("window")), page); ok {
+		respond(c, http.StatusOK, resp)
+	}
+}
+
+// browseListing loads a language's category listing from page, honouring
+// page_size, pages, enrich and fields. It responds itself when it fails.
+func browseListing(c *gin.Context, language, category, window string, page int) (models.BrowseResponse, bool) {
+	source, ok := requireProvider(c, language)
+	if !ok {
+		return models.BrowseResponse{}, false
+	}
+	if categories := source.info().Categories; !slices.Contains(categories, category) {
+		respondError(c, http.StatusBadRequest, "invalid_category", "category must be one of "+strings.Join(categories, ", "))
+		return models.BrowseResponse{}, false
+	}
+	switch windows := source.info().Windows; {
+	case window != "" && category != "popular":
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "window only applies to category=popular")
+		return models.BrowseResponse{}, false
+	case window != "" && !slices.Contains(windows, window):
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "window must be one of "+strings.Join(windows, ", "))
+		return models.BrowseResponse{}, false
+	case category == "popular" && window == "":
+		window = "alltime"
+	}
+	pageSize, ok := parsePageSize(c)
+	if !ok {
+		return models.BrowseResponse{}, false
+	}
+	page, pageCount, ok := parsePageSpan(c, page)
+	if !ok {
+		return models.BrowseResponse{}, false
+	}
+	enrich, ok := wantsEnrich(c)
+	if !ok {
+		return models.BrowseResponse{}, false
+	}
+	full, ok := wantsFullFields(c)
+	if !ok {
+		return models.BrowseResponse{}, false
+	}
+	fetch := func(ctx context.Context, page int) (scraper.Listing, error) {
+		return source.browse(ctx, language, category, window, page)
+	}
+	var pages pageRange
+	var err error
+	if pageCount > 0 {
+		pages, err = fetchPageSpan(c.Request.Context(), fetch, page, pageCount)
+	} else {
+		pages, err = fetchPages(c.Request.Context(), fetch, page, pageSize)
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return models.BrowseResponse{}, false
+	}
+	if enrich {
+		enrichMovies(c.Request.Context(), language, pages.Movies)
+	}
+	if !full {
+		basicFields(pages.Movies)
+	}
+	resp := browseResponse(category, language, page, pages)
+	resp.Window = window
+	return resp, true
+}
+
+// browseByRating lists movies matching the finder's per-aspect rating filters.
+func browseByRating(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	targetUrl := fmt.Sprintf(
+		"%s/movie/results/?lang=%s&find=Rating&action=%s&comedy=%s&romance=%s&storyline=%s&performance=%s&ratecount=%s",
+		scraper.Einthusan.BaseURL(), language,
+		c.DefaultQuery("action", "0"), c.DefaultQuery("comedy", "0"), c.DefaultQuery("romance", "0"),
+		c.DefaultQuery("storyline", "0"), c.DefaultQuery("performance", "0"), c.DefaultQuery("ratecount", "1"),
+	)
+	browseFinder(c, language, "Genre", targetUrl)
+}
+
+func browseDecade(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	decade := c.Param("decade")
+	targetUrl := fmt.Sprintf("%s/movie/results/?decade=%s&find=Decade&lang=%s", scraper.Einthusan.BaseURL(), decade, language)
+	browseFinder(c, language, "Decade: "+decade, targetUrl)
+}
+
+func browseYear(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	year := c.Param("year")
+	targetUrl := fmt.Sprintf("%s/movie/results/?find=Year&lang=%s&year=%s", scraper.Einthusan.BaseURL(), language, year)
+	browseFinder(c, language, "Year: "+year, targetUrl)
+}
+
+// browseFinder answers a finder listing at targetUrl, honouring page and
+// page_size.
+func browseFinder(c *gin.Context, language, category, targetUrl string) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, ok := parsePageSize(c)
+	if !ok {
+		return
+	}
+	full, ok := wantsFullFields(c)
+	if !ok {
+		return
+	}
+	pages, err := fetchPages(c.Request.Context(), urlPages(language, targetUrl), page, pageSize)
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	if !full {
+		basicFields(pages.Movies)
+	}
+	respond(c, http.StatusOK, browseResponse(category, language, page, pages))
+}
+
+func browseResponse(category, language string, page int, pages pageRange) models.BrowseResponse {
+	return models.BrowseResponse{
+		Category:       category,
+		HasMore:        pages.HasMore,
+		Language:       language,
+		Movies:         pages.Movies,
+		NextPage:       pages.nextPage(),
+		Page:           page,
+		PageSize:       scraper.PageSize(),
+		Count:          len(pages.Movies),
+		Reason:         listingReason(pages.Movies, pages.Degraded, reasonUpstreamEmpty),
+		TotalPages:     pages.TotalPages,
+		TotalResults:   pages.TotalResults,
+		ScrapeDegraded: pages.Degraded,
+	}
+}
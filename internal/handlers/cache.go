@@ -0,0 +1,563 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const (
+	defaultCacheTTLSeconds = 300
+	defaultCacheMaxEntries = 1000
+	cacheFlushInterval     = time.Minute
+
+	defaultNegativeCacheTTLSeconds = 60
+)
+
+// cacheEntry is a single scraped listing. It is also the on-disk format used
+// when CACHE_DIR is set, so ExpiresAt keeps the original TTL across restarts.
+type cacheEntry struct {
+	Language  string              `json:"language"`
+	Movies    []models.MovieEntry `json:"movies"`
+	Total     int                 `json:"total,omitempty"`
+	HasNext   bool                `json:"has_next,omitempty"`
+	LastPage  int                 `json:"last_page,omitempty"`
+	Heading   string              `json:"heading,omitempty"`
+	Degraded  bool                `json:"degraded,omitempty"`
+	StoredAt  time.Time           `json:"stored_at,omitzero"`
+	ExpiresAt time.Time           `json:"expires_at"`
+}
+
+func (e cacheEntry) listing() scraper.Listing {
+	return scraper.Listing{Movies: e.Movies, Total: e.Total, HasNext: e.HasNext, LastPage: e.LastPage, Heading: e.Heading, Degraded: e.Degraded}
+}
+
+type scrapeCache struct {
+	mu         sync.RWMutex
+	entries    map[string]cacheEntry
+	ttl        time.Duration
+	kindTTLs   map[string]time.Duration // Overrides ttl per cacheKind
+	maxEntries int                      // 0 or less means unbounded
+}
+
+// cache holds scraped listings for CACHE_TTL_SECONDS, keeping at most
+// CACHE_MAX_ENTRIES of them. Search results, actor filmographies and browse
+// listings change at different rates, so each kind can have its own TTL in
+// CACHE_TTL_SEARCH_SECONDS, CACHE_TTL_ACTOR_SECONDS and
+// CACHE_TTL_BROWSE_SECONDS.
+var cache = newScrapeCache(
+	time.Duration(EnvInt("CACHE_TTL_SECONDS", defaultCacheTTLSeconds))*time.Second,
+	EnvInt("CACHE_MAX_ENTRIES", defaultCacheMaxEntries),
+)
+
+func newScrapeCache(ttl time.Duration, maxEntries int) *scrapeCache {
+	kindTTLs := make(map[string]time.Duration)
+	for _, kind := range []string{cacheKindSearch, cacheKindActor, cacheKindBrowse} {
+		secs := EnvInt("CACHE_TTL_"+strings.ToUpper(kind)+"_SECONDS", int(ttl/time.Second))
+		kindTTLs[kind] = time.Duration(secs) * time.Second
+	}
+	return &scrapeCache{entries: make(map[string]cacheEntry), ttl: ttl, kindTTLs: kindTTLs, maxEntries: maxEntries}
+}
+
+const (
+	cacheKindSearch = "search"
+	cacheKindActor  = "actor"
+	cacheKindBrowse = "browse"
+)
+
+// cacheKind classifies an upstream listing URL by the endpoint family it serves.
+func cacheKind(target string) string {
+	switch {
+	case strings.Contains(target, "query="):
+		return cacheKindSearch
+	case strings.Contains(target, "find=Cast"):
+		return cacheKindActor
+	default:
+		return cacheKindBrowse
+	}
+}
+
+// ttlFor is how long a listing scraped from url stays fresh.
+func (sc *scrapeCache) ttlFor(url string) time.Duration {
+	if ttl, ok := sc.kindTTLs[cacheKind(url)]; ok {
+		return ttl
+	}
+	return sc.ttl
+}
+
+func (sc *scrapeCache) get(url string) (scraper.Listing, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	entry, ok := sc.entries[url]
+	if !ok || time.Now().After(entry.ExpiresAt) {
+		return scraper.Listing{}, false
+	}
+	return entry.listing(), true
+}
+
+// getStale returns an entry regardless of its TTL, for use when the
+// upstream is failing and old data beats no data.
+func (sc *scrapeCache) getStale(url string) (scraper.Listing, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	entry, ok := sc.entries[url]
+	if !ok {
+		return scraper.Listing{}, false
+	}
+	return entry.listing(), true
+}
+
+func (sc *scrapeCache) set(language, url string, result scraper.Listing) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.put(url, cacheEntry{Language: language, Movies: result.Movies, Total: result.Total, HasNext: result.HasNext, LastPage: result.LastPage, Heading: result.Heading, Degraded: result.Degraded, StoredAt: time.Now(), ExpiresAt: time.Now().Add(sc.ttlFor(url))})
+}
+
+// put stores entry, making room first if the cache is full. Expired entries
+// go first; if that isn't enough, the one closest to expiry is dropped.
+// Callers must hold sc.mu.
+func (sc *scrapeCache) put(url string, entry cacheEntry) {
+	if _, ok := sc.entries[url]; !ok && sc.maxEntries > 0 && len(sc.entries) >= sc.maxEntries {
+		now := time.Now()
+		for key, e := range sc.entries {
+			if now.After(e.ExpiresAt) {
+				delete(sc.entries, key)
+			}
+		}
+		if len(sc.entries) >= sc.maxEntries {
+			oldest := ""
+			for key, e := range sc.entries {
+				if oldest == "" || e.ExpiresAt.Before(sc.entries[oldest].ExpiresAt) {
+					oldest = key
+				}
+			}
+			delete(sc.entries, oldest)
+		}
+	}
+	sc.entries[url] = entry
+}
+
+// flush evicts entries for language (case-insensitive) and/or whose URL
+// starts with prefix; with neither it empties the cache. It returns the
+// number of entries removed.
+func (sc *scrapeCache) flush(language, prefix string) int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	evicted := 0
+	for url, entry := range sc.entries {
+		if language != "" && !strings.EqualFold(entry.Language, language) {
+			continue
+		}
+		if prefix != "" && !strings.HasPrefix(url, prefix) {
+			continue
+		}
+		delete(sc.entries, url)
+		evicted++
+	}
+	return evicted
+}
+
+// evict removes the entry for url, reporting whether there was one.
+func (sc *scrapeCache) evict(url string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	_, ok := sc.entries[url]
+	delete(sc.entries, url)
+	return ok
+}
+
+// CachedListing describes one cache entry for /admin/cache.
+type CachedListing struct {
+	URL              string `json:"url"`
+	Language         string `json:"language"`
+	Kind             string `json:"kind"`
+	Movies           int    `json:"movies"`
+	Bytes            int    `json:"bytes"` // Size of the entry as JSON
+	AgeSeconds       int    `json:"age_seconds"`
+	ExpiresInSeconds int    `json:"expires_in_seconds"` // Negative once expired; expired entries stay to be served stale
+}
+
+// inspect lists the entries flush(language, prefix) would evict, by URL.
+func (sc *scrapeCache) inspect(language, prefix string) []CachedListing {
+	now := time.Now()
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	out := []CachedListing{}
+	for url, entry := range sc.entries {
+		if language != "" && !strings.EqualFold(entry.Language, language) {
+			continue
+		}
+		if prefix != "" && !strings.HasPrefix(url, prefix) {
+			continue
+		}
+		// Entries persisted before StoredAt was recorded are dated from their TTL.
+		stored := entry.StoredAt
+		if stored.IsZero() {
+			stored = entry.ExpiresAt.Add(-sc.ttlFor(url))
+		}
+		data, _ := json.Marshal(entry)
+		out = append(out, CachedListing{
+			URL:              url,
+			Language:         entry.Language,
+			Kind:             cacheKind(url),
+			Movies:           len(entry.Movies),
+			Bytes:            len(data),
+			AgeSeconds:       int(now.Sub(stored).Seconds()),
+			ExpiresInSeconds: int(entry.ExpiresAt.Sub(now).Seconds()),
+		})
+	}
+	slices.SortFunc(out, func(a, b CachedListing) int { return strings.Compare(a.URL, b.URL) })
+	return out
+}
+
+// cachedScrape serves a listing from the request memo or the shared cache,
+// scraping and storing it on a miss. If the scrape fails but an expired entry
+// is still held, that stale entry is served instead. Callers get their own
+// copy of the movie slice, so sorting or filtering it never touches the
+// cached listing.
+func cachedScrape(ctx context.Context, language, url string) (scraper.Listing, error) {
+	scope := scopeFrom(ctx)
+	if result, ok := scope.get(url); ok {
+		stats.memoHits.Add(1)
+		return result.Clone(), nil
+	}
+	if result, ok := cache.get(url); ok {
+		stats.cacheHits.Add(1)
+		scope.set(url, result, cacheHit)
+		return result.Clone(), nil
+	}
+	result, err := upstream.ScrapeListing(ctx, url)
+	if err != nil {
+		if stale, ok := cache.getStale(url); ok {
+			scope.set(url, stale, cacheStale)
+			return stale.Clone(), nil
+		}
+		return scraper.Listing{}, err
+	}
+	cache.set(language, url, result)
+	localIndex.add(language, result.Movies)
+	scope.set(url, result, cacheMiss)
+	return result.Clone(), nil
+}
+
+type scopeKey struct{}
+
+// Cache statuses reported in X-Cache, ordered so a higher value wins when a
+// request resolves several listings.
+const (
+	cacheNone = iota
+	cacheHit
+	cacheMiss
+	cacheStale
+)
+
+var cacheStatusNames = map[int]string{cacheHit: "HIT", cacheMiss: "MISS", cacheStale: "STALE"}
+
+// requestScope memoizes listings for the lifetime of one request, so composite
+// handlers that ask for the same URL twice only resolve it once. It also
+// tracks how the request's listings were served, for the X-Cache header, and
+// how many upstream fetches it has made, for chargeFetch.
+type requestScope struct {
+	mu       sync.Mutex
+	listings map[string]scraper.Listing
+	status   int
+	fetches  int
+}
+
+// withRequestScope attaches a fresh requestScope to every request context.
+func withRequestScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := &requestScope{listings: make(map[string]scraper.Listing)}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), scopeKey{}, scope))
+		c.Next()
+	}
+}
+
+// scopeFrom returns the request's scope, or nil outside a request. A nil
+// scope is valid and simply never remembers anything.
+func scopeFrom(ctx context.Context) *requestScope {
+	scope, _ := ctx.Value(scopeKey{}).(*requestScope)
+	return scope
+}
+
+func (rs *requestScope) get(url string) (scraper.Listing, bool) {
+	if rs == nil {
+		return scraper.Listing{}, false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	result, ok := rs.listings[url]
+	return result, ok
+}
+
+func (rs *requestScope) set(url string, result scraper.Listing, status int) {
+	if rs == nil {
+		return
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.listings[url] = result
+	rs.status = max(rs.status, status)
+}
+
+// chargeFetch takes a scrapeLimiter token for every upstream fetch the
+// request makes after its first, which limitScrapes already paid for, so a
+// batch or GraphQL query that fans out to many pages costs what the same
+// fetches made one request at a time would. It waits for the token rather
+// than failing, unless the request's deadline comes first.
+func (rs *requestScope) chargeFetch(ctx context.Context) error {
+	if rs == nil {
+		return nil
+	}
+	rs.mu.Lock()
+	rs.fetches++
+	paid := rs.fetches == 1
+	rs.mu.Unlock()
+	if paid {
+		return nil
+	}
+	if err := scrapeLimiter.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", errUpstreamBusy, err)
+	}
+	return nil
+}
+
+// cacheStatus returns HIT, MISS or STALE, or "" if nothing was looked up.
+func (rs *requestScope) cacheStatus() string {
+	if rs == nil {
+		return ""
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return cacheStatusNames[rs.status]
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// cacheFileName maps a language to its persistence file. The language comes
+// straight from the request path, so anything unusual is folded into one file.
+func cacheFileName(language string) string {
+	name := strings.ToLower(language)
+	if name == "" || unsafeFileChars.MatchString(name) {
+		name = "_other"
+	}
+	return name + ".json"
+}
+
+// save writes every live entry to dir, one JSON file per language.
+func (sc *scrapeCache) save(dir string) error {
+	now := time.Now()
+	byFile := make(map[string]map[string]cacheEntry)
+	sc.mu.RLock()
+	for url, entry := range sc.entries {
+		if now.After(entry.ExpiresAt) {
+			continue
+		}
+		file := cacheFileName(entry.Language)
+		if byFile[file] == nil {
+			byFile[file] = make(map[string]cacheEntry)
+		}
+		byFile[file][url] = entry
+	}
+	sc.mu.RUnlock()
+
+	// Drop files for languages that no longer have entries, e.g. after a flush,
+	// so a restart doesn't resurrect them.
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range existing {
+		if _, ok := byFile[filepath.Base(path)]; !ok {
+			os.Remove(path)
+		}
+	}
+
+	for file, entries := range byFile {
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return err
+		}
+		// Write to a temp file first so a crash mid-flush never leaves a truncated cache.
+		tmp := filepath.Join(dir, file+".tmp")
+		if err := os.WriteFile(tmp, data, 0o644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, file)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads the per-language files in dir, skipping entries whose TTL has
+// already run out. It returns the number of entries restored.
+func (sc *scrapeCache) load(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	loaded := 0
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return loaded, err
+		}
+		var entries map[string]cacheEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			log.Printf("cache: skipping unreadable %s: %v", file, err)
+			continue
+		}
+		for url, entry := range entries {
+			if now.After(entry.ExpiresAt) {
+				continue
+			}
+			sc.put(url, entry)
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+// cacheDir is where persistCache writes, or "" when persistence is off.
+var cacheDir string
+
+// StartCachePersistence restores the cache from dir and then flushes it back
+// periodically. It is a no-op when dir is empty.
+func StartCachePersistence(dir string) {
+	if dir == "" {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Printf("cache: persistence disabled, cannot create %s: %v", dir, err)
+		return
+	}
+	loaded, err := cache.load(dir)
+	if err != nil {
+		log.Printf("cache: failed to load from %s: %v", dir, err)
+	}
+	log.Printf("cache: restored %d entries from %s", loaded, dir)
+	cacheDir = dir
+
+	go func() {
+		ticker := time.NewTicker(cacheFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			persistCache()
+		}
+	}()
+}
+
+// persistCache flushes the cache to CACHE_DIR, if persistence is on. serve
+// calls it once more on shutdown, so a deploy keeps what was scraped since
+// the last tick.
+func persistCache() {
+	if cacheDir == "" {
+		return
+	}
+	if err := cache.save(cacheDir); err != nil {
+		log.Printf("cache: flush to %s failed: %v", cacheDir, err)
+	}
+}
+
+// maxTTLCacheItems caps every ttlCache. Several are keyed by IDs taken
+// straight from the request, so without a cap a client asking for random
+// IDs would grow them without limit.
+const maxTTLCacheItems = 10000
+
+// ttlCache is a small expiring map for lookups that don't need persistence.
+// It holds at most maxItems entries.
+type ttlCache[V any] struct {
+	mu       sync.Mutex
+	items    map[string]ttlItem[V]
+	ttl      time.Duration
+	maxItems int
+}
+
+type ttlItem[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	return &ttlCache[V]{items: make(map[string]ttlItem[V]), ttl: ttl, maxItems: maxTTLCacheItems}
+}
+
+func (tc *ttlCache[V]) get(key string) (V, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	item, ok := tc.items[key]
+	if !ok || time.Now().After(item.expiresAt) {
+		delete(tc.items, key)
+		var zero V
+		return zero, false
+	}
+	return item.value, true
+}
+
+// set stores value under key, making room first if the cache is full, as
+// scrapeCache.put does: expired items go first, then the one closest to
+// expiry.
+func (tc *ttlCache[V]) set(key string, value V) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	now := time.Now()
+	if _, ok := tc.items[key]; !ok && len(tc.items) >= tc.maxItems {
+		for k, item := range tc.items {
+			if now.After(item.expiresAt) {
+				delete(tc.items, k)
+			}
+		}
+		if len(tc.items) >= tc.maxItems {
+			oldest := ""
+			for k, item := range tc.items {
+				if oldest == "" || item.expiresAt.Before(tc.items[oldest].expiresAt) {
+					oldest = k
+				}
+			}
+			delete(tc.items, oldest)
+		}
+	}
+	tc.items[key] = ttlItem[V]{value: value, expiresAt: now.Add(tc.ttl)}
+}
+
+// missingIDs remembers IDs the upstream answered with a 404, so repeat lookups
+// fail fast. The TTL is kept short so a newly added title is not hidden for long.
+var missingIDs = newTTLCache[struct{}](time.Duration(EnvInt("NEGATIVE_CACHE_TTL_SECONDS", defaultNegativeCacheTTLSeconds)) * time.Second)
+
+func markMissing(kind, id string) {
+	missingIDs.set(kind+"/"+id, struct{}{})
+}
+
+func isKnownMissing(kind, id string) bool {
+	_, ok := missingIDs.get(kind + "/" + id)
+	return ok
+}
+
+// clear empties the cache and returns how many items it held.
+func (tc *ttlCache[V]) clear() int {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	n := len(tc.items)
+	clear(tc.items)
+	return n
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+func TestScrapeCacheSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	saved := newScrapeCache(time.Hour, 0)
+	tamil := scraper.Listing{Movies: []models.MovieEntry{{ID: "a1", Title: "Theri", Year: 2016}, {ID: "a2", Title: "Kaththi"}}, Total: 42, HasNext: true, LastPage: 3, Heading: "Vijay"}
+	hindi := scraper.Listing{Movies: []models.MovieEntry{{ID: "b1", Title: "Dangal"}}}
+	saved.set("tamil", "https://einthusan.tv/movie/results/?lang=tamil&find=Cast&id=1", tamil)
+	saved.set("hindi", "https://einthusan.tv/movie/browse/?lang=hindi", hindi)
+	if err := saved.save(dir); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded := newScrapeCache(time.Hour, 0)
+	n, err := loaded.load(dir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("load restored %d entries, want 2", n)
+	}
+	for url, want := range map[string]scraper.Listing{
+		"https://einthusan.tv/movie/results/?lang=tamil&find=Cast&id=1": tamil,
+		"https://einthusan.tv/movie/browse/?lang=hindi":                 hindi,
+	} {
+		got, ok := loaded.get(url)
+		if !ok {
+			t.Errorf("get(%q) missed after load", url)
+			continue
+		}
+		if !slices.Equal(got.Movies, want.Movies) || got.Total != want.Total || got.HasNext != want.HasNext || got.LastPage != want.LastPage || got.Heading != want.Heading {
+			t.Errorf("get(%q) = %+v, want %+v", url, got, want)
+		}
+	}
+}
+
+func TestPersistCache(t *testing.T) {
+	dir := t.TempDir()
+	previous := cacheDir
+	t.Cleanup(func() {
+		cacheDir = previous
+		cache.flush("", "")
+	})
+
+	url := "https://einthusan.tv/movie/browse/?lang=tamil"
+	cache.set("tamil", url, scraper.Listing{Movies: []models.MovieEntry{{ID: "a1", Title: "Theri"}}})
+	cacheDir = ""
+	persistCache()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("persistence off, but %d files were written", len(entries))
+	}
+
+	cacheDir = dir
+	persistCache()
+	loaded := newScrapeCache(time.Hour, 0)
+	if n, err := loaded.load(dir); err != nil || n != 1 {
+		t.Fatalf("load restored %d entries (err %v), want 1", n, err)
+	}
+	if _, ok := loaded.get(url); !ok {
+		t.Errorf("get(%q) missed after persistCache", url)
+	}
+}
+
+func TestScrapeCacheLoadDropsExpired(t *testing.T) {
+	dir := t.TempDir()
+	entries := map[string]cacheEntry{
+		"https://einthusan.tv/live":    {Language: "tamil", Movies: []models.MovieEntry{{ID: "a1"}}, ExpiresAt: time.Now().Add(time.Hour)},
+		"https://einthusan.tv/expired": {Language: "tamil", Movies: []models.MovieEntry{{ID: "a2"}}, ExpiresAt: time.Now().Add(-time.Minute)},
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tamil.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sc := newScrapeCache(time.Hour, 0)
+	n, err := sc.load(dir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("load restored %d entries, want 1", n)
+	}
+	if _, ok := sc.get("https://einthusan.tv/live"); !ok {
+		t.Error("live entry missing after load")
+	}
+	if _, ok := sc.getStale("https://einthusan.tv/expired"); ok {
+		t.Error("expired entry was restored")
+	}
+}
+
+func TestMissingIDIsNotScrapedAgain(t *testing.T) {
+	hits := fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	for i := range 2 {
+		if _, err := scrapeMovieDetail(t.Context(), "tamil", "nosuchid"); !errors.Is(err, scraper.ErrNotFound) {
+			t.Fatalf("lookup %d: err = %v, want errNotFound", i+1, err)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream hit %d times, want 1", n)
+	}
+}
+
+func TestRequestScopeMemoizesDuplicateFetch(t *testing.T) {
+	hits := fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(fixtureDir, "results.html"))
+	})
+	ctx := context.WithValue(t.Context(), scopeKey{}, &requestScope{listings: make(map[string]scraper.Listing)})
+	url := browseUrlFor("tamil", "recent")
+	first, err := cachedScrape(ctx, "tamil", url)
+	if err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	// Emptying the shared cache leaves only the request's own memo to answer.
+	cache.flush("", "")
+	second, err := cachedScrape(ctx, "tamil", url)
+	if err != nil {
+		t.Fatalf("second fetch: %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream hit %d times, want 1", n)
+	}
+	if len(first.Movies) != 20 || !slices.Equal(first.Movies, second.Movies) {
+		t.Errorf("second fetch returned %d movies, first %d", len(second.Movies), len(first.Movies))
+	}
+}
+
+func TestTTLCacheIsCapped(t *testing.T) {
+	tc := newTTLCache[struct{}](time.Minute)
+	tc.maxItems = 3
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		tc.set(key, struct{}{})
+	}
+	if n := len(tc.items); n != 3 {
+		t.Errorf("cache holds %d items, want 3", n)
+	}
+	if _, ok := tc.get("e"); !ok {
+		t.Error("newest item was evicted")
+	}
+}
+
+func TestXCacheHeader(t *testing.T) {
+	hits := fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(fixtureDir, "results.html"))
+	})
+	router := NewRouter()
+	for _, want := range []string{"MISS", "HIT"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/language/tamil", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d; body %s", w.Code, w.Body)
+		}
+		if got := w.Header().Get("X-Cache"); got != want {
+			t.Errorf("X-Cache = %q, want %q", got, want)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream hit %d times, want 1", n)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+// catalogSnapshot holds the last two distinct versions of a language's recent
+// listing. It only rolls forward when the listing changes, so repeated calls
+// keep reporting the same changeset until something new appears.
+type catalogSnapshot struct {
+	Previous  []models.MovieEntry `json:"previous"`
+	Current   []models.MovieEntry `json:"current"`
+	ChangedAt time.Time           `json:"changed_at"`
+}
+
+type ChangesResponse struct {
+	Language  string              `json:"language"`
+	Added     []models.MovieEntry `json:"added"`
+	Removed   []models.MovieEntry `json:"removed"`
+	ChangedAt time.Time           `json:"changed_at"`
+}
+
+var (
+	snapshotsMu sync.Mutex
+	snapshots   = make(map[string]*catalogSnapshot)
+	snapshotDir string
+)
+
+// InitSnapshots persists snapshots under dir/snapshots. With an empty dir
+// they live in memory only.
+func InitSnapshots(dir string) {
+	if dir == "" {
+		return
+	}
+	snapshotDir = filepath.Join(dir, "snapshots")
+	if err := os.MkdirAll(snapshotDir, 0o755); err != nil {
+		log.Printf("snapshots: persistence disabled, cannot create %s: %v", snapshotDir, err)
+		snapshotDir = ""
+	}
+}
+
+// catalogChanges compares the current recent listing with the stored
+// snapshot and returns what was added and removed since it last changed.
+func catalogChanges(ctx context.Context, language string) (*ChangesResponse, error) {
+	targetUrl, _ := browseUrl(language, "recent")
+	result, err := cachedScrape(ctx, language, targetUrl)
+	if err != nil {
+		return nil, err
+	}
+	movies := result.Movies
+
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	snap := loadSnapshot(language)
+	if snap == nil {
+		// First run: everything is the baseline, nothing has changed yet.
+		snap = &catalogSnapshot{Current: movies, ChangedAt: time.Now()}
+		storeSnapshot(language, snap)
+	} else if !sameMovies(snap.Current, movies) {
+		snap = &catalogSnapshot{Previous: snap.Current, Current: movies, ChangedAt: time.Now()}
+		storeSnapshot(language, snap)
+	}
+
+	resp := &ChangesResponse{Language: language, Added: []models.MovieEntry{}, Removed: []models.MovieEntry{}, ChangedAt: snap.ChangedAt}
+	if snap.Previous == nil {
+		return resp, nil
+	}
+	resp.Added = missingFrom(snap.Current, snap.Previous)
+	resp.Removed = missingFrom(snap.Previous, snap.Current)
+	return resp, nil
+}
+
+// loadSnapshot returns the in-memory snapshot, reading it from disk the first
+// time a language is seen. Callers must hold snapshotsMu.
+func loadSnapshot(language string) *catalogSnapshot {
+	if snap, ok := snapshots[language]; ok {
+		return snap
+	}
+	if snapshotDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(snapshotDir, cacheFileName(language)))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("snapshots: failed to read %s: %v", language, err)
+		}
+		return nil
+	}
+	var snap catalogSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		log.Printf("snapshots: skipping unreadable %s: %v", language, err)
+		return nil
+	}
+	snapshots[language] = &snap
+	return &snap
+}
+
+// storeSnapshot records snap in memory and on disk. Callers must hold snapshotsMu.
+func storeSnapshot(language string, snap *catalogSnapshot) {
+	snapshots[language] = snap
+	if snapshotDir == "" {
+		return
+	}
+	data, err := json.Marshal(snap)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(snapshotDir, cacheFileName(language)), data, 0o644)
+	}
+	if err != nil {
+		log.Printf("snapshots: failed to save %s: %v", language, err)
+	}
+}
+
+func sameMovies(a, b []models.MovieEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key() != b[i].Key() {
+			return false
+		}
+	}
+	return true
+}
+
+// missingFrom returns the entries of movies that aren't in other.
+func missingFrom(movies, other []models.MovieEntry) []models.MovieEntry {
+	known := make(map[string]bool, len(other))
+	for _, m := range other {
+		known[m.Key()] = true
+	}
+	diff := []models.MovieEntry{}
+	for _, m := range movies {
+		if !known[m.Key()] {
+			diff = append(diff, m)
+		}
+	}
+	return diff
+}
+
+func listChanges(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	changes, err := catalogChanges(c.Request.Context(), language)
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	respond(c, http.StatusOK, changes)
+}
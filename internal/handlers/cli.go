@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const cliUsage = `usage: app <command> [arguments] [flags]
+
+Scrapes once and prints the same JSON the API would answer with, instead of
+starting the server. Settings are read as the server reads them.
+
+commands:
+  search <language> <query>     search one language
+  browse <language>             recent or popular movies
+  actor  <language> <actorcode> an actor's filmography
+  movie  <language> <id>        one movie's details
+
+Run app <command> -h for the command's flags.
+`
+
+// cliCommands maps each subcommand to the positional arguments it takes and
+// the func that runs it.
+var cliCommands = map[string]struct {
+	args []string
+	run  func(ctx context.Context, source provider, args []string, opts cliOptions) (any, error)
+}{
+	"search": {[]string{"language", "query"}, cliSearch},
+	"browse": {[]string{"language"}, cliBrowse},
+	"actor":  {[]string{"language", "actorcode"}, cliActor},
+	"movie":  {[]string{"language", "id"}, cliMovie},
+}
+
+// cliOptions are the flags shared by every command; each command reads the
+// ones that apply to it.
+type cliOptions struct {
+	page, pageSize, limit int
+	category, window      string
+	sort                  string
+	full, strict          bool
+}
+
+// RunCLI runs one command from args, such as search tamil "theri", and
+// returns the process exit code: 0 on success, 1 when the scrape fails and 2
+// for a usage error. Logs go to stderr at warn and above unless LOG_LEVEL
+// says otherwise, so stdout is only the JSON.
+func RunCLI(args []string) int {
+	ConfigureLogging(cmp.Or(Setting("LOG_LEVEL"), "warn"))
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(os.Stdout, cliUsage)
+		return 0
+	}
+	command, ok := cliCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], cliUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: app %s <%s> [flags]\n", args[0], strings.Join(command.args, "> <"))
+		fs.PrintDefaults()
+	}
+	var opts cliOptions
+	fs.IntVar(&opts.page, "page", 1, "upstream page to start from")
+	fs.IntVar(&opts.pageSize, "page-size", 0, "keep taking whole pages until at least this many movies (browse, actor)")
+	fs.IntVar(&opts.limit, "limit", 0, "return at most this many results (search)")
+	fs.StringVar(&opts.category, "category", "recent", "recent or popular (browse)")
+	fs.StringVar(&opts.window, "window", "", "week, month, year or alltime, for --category popular (browse)")
+	fs.StringVar(&opts.sort, "sort", "relevance", "relevance, title or year (search)")
+	fs.BoolVar(&opts.full, "full", false, "include duration, synopsis and views")
+	fs.BoolVar(&opts.strict, "strict", false, "rank by titles as written, without transliteration folding (search)")
+	providerName := fs.String("provider", defaultProvider, "source site to read from")
+	positional, err := parseInterspersed(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	if len(positional) != len(command.args) {
+		fs.Usage()
+		return 2
+	}
+
+	source, ok := providers[*providerName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unsupported provider %q; supported: %s\n", *providerName, strings.Join(providerNames(), ", "))
+		return 2
+	}
+	positional[0] = strings.ToLower(positional[0])
+	if info := source.info(); !slices.Contains(info.Languages, positional[0]) {
+		fmt.Fprintf(os.Stderr, "%s does not serve %q; supported: %s\n", info.Name, positional[0], strings.Join(info.Languages, ", "))
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := command.run(ctx, source, positional, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	if err := writeJSON(os.Stdout, result); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// parseInterspersed parses fs from args, allowing flags after positional
+// arguments as in browse telugu --category popular, and returns the
+// positional ones. The flag package alone stops at the first of them.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+func cliSearch(ctx context.Context, source provider, args []string, opts cliOptions) (any, error) {
+	query := strings.Join(strings.Fields(args[1]), " ")
+	if query == "" {
+		return nil, errors.New("query is empty")
+	}
+	if opts.sort != "relevance" && opts.sort != "title" && opts.sort != "year" {
+		return nil, errors.New("--sort must be relevance, title or year")
+	}
+	resp, err := rankedSearch(ctx, source, args[0], query, opts.page, searchOptions{order: opts.sort, limit: max(opts.limit, 0), strict: opts.strict})
+	if err != nil {
+		return nil, err
+	}
+	if !opts.full {
+		basicFields(resp.Movies)
+	}
+	return resp, nil
+}
+
+func cliBrowse(ctx context.Context, source provider, args []string, opts cliOptions) (any, error) {
+	language, category, window := args[0], strings.ToLower(opts.category), strings.ToLower(opts.window)
+	info := source.info()
+	switch {
+	case !slices.Contains(info.Categories, category):
+		return nil, fmt.Errorf("--category must be one of %s", strings.Join(info.Categories, ", "))
+	case window != "" && category != "popular":
+		return nil, errors.New("--window only applies to --category popular")
+	case window != "" && !slices.Contains(info.Windows, window):
+		return nil, fmt.Errorf("--window must be one of %s", strings.Join(info.Windows, ", "))
+	case category == "popular" && window == "":
+		window = "alltime"
+	}
+	pages, err := fetchPages(ctx, func(ctx context.Context, page int) (scraper.Listing, error) {
+		return source.browse(ctx, language, category, window, page)
+	}, opts.page, min(opts.pageSize, maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+	if !opts.full {
+		basicFields(pages.Movies)
+	}
+	resp := browseResponse(category, language, opts.page, pages)
+	resp.Window = window
+	return resp, nil
+}
+
+func cliActor(ctx context.Context, source provider, args []string, opts cliOptions) (any, error) {
+	language, actorCode := args[0], args[1]
+	pages, err := fetchPages(ctx, func(ctx context.Context, page int) (scraper.Listing, error) {
+		return source.byActor(ctx, language, actorCode, page)
+	}, opts.page, min(opts.pageSize, maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(pages.Movies) == 0 && pages.Heading == "" && !pages.Degraded {
+		return nil, errors.New("actor not found")
+	}
+	if !opts.full {
+		basicFields(pages.Movies)
+	}
+	return models.ActorResponse{
+		ActorID:        actorCode,
+		ActorName:      resolveActorName(ctx, language, actorCode, pages.Heading),
+		HasMore:        pages.HasMore,
+		Language:       language,
+		Movies:         pages.Movies,
+		NextPage:       pages.nextPage(),
+		Page:           opts.page,
+		PageSize:       scraper.PageSize(),
+		Count:          len(pages.Movies),
+		Reason:         listingReason(pages.Movies, pages.Degraded, reasonUpstreamEmpty),
+		TotalPages:     pages.TotalPages,
+		TotalResults:   pages.TotalResults,
+		ScrapeDegraded: pages.Degraded,
+	}, nil
+}
+
+func cliMovie(ctx context.Context, source provider, args []string, _ cliOptions) (any, error) {
+	detail, err := source.details(ctx, args[0], args[1])
+	if errors.Is(err, scraper.ErrNotFound) {
+		return nil, errors.New("movie not found")
+	}
+	return detail, err
+}
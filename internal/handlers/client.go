@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+const (
+	defaultUserAgent      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	defaultAcceptLanguage = "en-US,en;q=0.9"
+)
+
+// userAgent and acceptLanguage are sent on every outbound request, from
+// UPSTREAM_USER_AGENT and UPSTREAM_ACCEPT_LANGUAGE when set. Einthusan
+// answers Go's default agent with 403s or a Cloudflare challenge, so the
+// default presents as a current browser.
+var (
+	userAgent      = cmp.Or(Setting("UPSTREAM_USER_AGENT"), defaultUserAgent)
+	acceptLanguage = cmp.Or(Setting("UPSTREAM_ACCEPT_LANGUAGE"), defaultAcceptLanguage)
+)
+
+// headerOverrides lets a request replace the outbound User-Agent and
+// Accept-Language with its X-Upstream-User-Agent and
+// X-Upstream-Accept-Language headers. It is off unless
+// UPSTREAM_HEADER_OVERRIDE is set, since it hands clients control over what
+// we send upstream. Cached listings are shared, so an override only applies
+// to pages this request actually fetches.
+var headerOverrides = envBool("UPSTREAM_HEADER_OVERRIDE", false)
+
+type overrideKey struct{}
+
+type upstreamOverride struct {
+	userAgent, acceptLanguage string
+}
+
+// withHeaderOverrides attaches the request's X-Upstream-* headers to its
+// context for fetchOnce.
+func withHeaderOverrides() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		override := upstreamOverride{userAgent: c.GetHeader("X-Upstream-User-Agent"), acceptLanguage: c.GetHeader("X-Upstream-Accept-Language")}
+		if override != (upstreamOverride{}) {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), overrideKey{}, override))
+		}
+		c.Next()
+	}
+}
+
+// upstreamTimeout bounds a single outbound request, so a hung Einthusan
+// can't pile up goroutines behind it.
+const upstreamTimeout = 15 * time.Second
+
+const (
+	defaultUpstreamConnectTimeoutMs = 5000
+	defaultUpstreamReadTimeoutMs    = 10000
+	defaultUpstreamIdleConns        = 16
+)
+
+// httpClient is shared by every outbound scrape, so connections to Einthusan
+// are kept alive and reused across requests.
+var httpClient = &http.Client{Timeout: upstreamTimeout, Transport: newUpstreamTransport()}
+
+// newUpstreamTransport bounds each phase of a request on its own:
+// UPSTREAM_CONNECT_TIMEOUT_MS for dialling and the TLS handshake, and
+// UPSTREAM_READ_TIMEOUT_MS for the response headers once the request is sent.
+// UPSTREAM_IDLE_CONNS caps the idle connections kept per host.
+func newUpstreamTransport() *http.Transport {
+	connect := time.Duration(EnvInt("UPSTREAM_CONNECT_TIMEOUT_MS", defaultUpstreamConnectTimeoutMs)) * time.Millisecond
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connect
+	transport.ResponseHeaderTimeout = time.Duration(EnvInt("UPSTREAM_READ_TIMEOUT_MS", defaultUpstreamReadTimeoutMs)) * time.Millisecond
+	transport.MaxIdleConnsPerHost = max(EnvInt("UPSTREAM_IDLE_CONNS", defaultUpstreamIdleConns), 1)
+	transport.IdleConnTimeout = 90 * time.Second
+	return transport
+}
+
+// ConfigureScrapeProxy routes httpClient through the given http(s):// or
+// socks5:// proxy. A comma-separated list rotates through the proxies, one
+// outbound request each. An empty value leaves scrapes going out directly.
+func ConfigureScrapeProxy(raw string) error {
+	var proxies []*url.URL
+	for _, proxy := range splitList(raw) {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid SCRAPE_PROXY %q: %w", proxy, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("invalid SCRAPE_PROXY %q: scheme must be http, https or socks5", proxy)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("invalid SCRAPE_PROXY %q: missing host", proxy)
+		}
+		proxies = append(proxies, u)
+	}
+	switch len(proxies) {
+	case 0:
+	case 1:
+		httpClient.Transport.(*http.Transport).Proxy = http.ProxyURL(proxies[0])
+	default:
+		var next atomic.Uint64
+		httpClient.Transport.(*http.Transport).Proxy = func(*http.Request) (*url.URL, error) {
+			return proxies[(next.Add(1)-1)%uint64(len(proxies))], nil
+		}
+	}
+	return nil
+}
+
+// defaultRetryAfter is how long scrapes pause after a 429 that doesn't say.
+// maxRetryAfter caps what one may ask for, so a bogus header can't stop
+// scraping for days.
+const (
+	defaultRetryAfter = 30 * time.Second
+	maxRetryAfter     = 10 * time.Minute
+)
+
+const (
+	defaultUpstreamAttempts    = 3
+	defaultUpstreamRetryBaseMs = 200
+)
+
+// Transient failures (connection errors and 5xx) are retried up to
+// UPSTREAM_ATTEMPTS times in total, waiting UPSTREAM_RETRY_BASE_MS, then
+// twice that, and so on, each plus up to the same again in jitter.
+var (
+	upstreamAttempts  = max(EnvInt("UPSTREAM_ATTEMPTS", defaultUpstreamAttempts), 1)
+	upstreamRetryBase = time.Duration(EnvInt("UPSTREAM_RETRY_BASE_MS", defaultUpstreamRetryBaseMs)) * time.Millisecond
+)
+
+// backoffError is returned while Einthusan has asked us to slow down, or
+// while the circuit breaker is open.
+type backoffError struct {
+	until   time.Time
+	circuit bool // the breaker, not a 429, is holding fetches back
+}
+
+func (e *backoffError) Error() string {
+	if e.circuit {
+		return fmt.Sprintf("upstream circuit open until %s", e.until.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("upstream rate limited until %s", e.until.Format(time.RFC3339))
+}
+
+// upstreamBackoff is shared by every handler, so one 429 pauses all scrapes
+// rather than each caller retrying on its own.
+var upstreamBackoff struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// fetchUpstream GETs an Einthusan page, honouring any active backoff and
+// starting one when the upstream answers 429. Cancelling ctx, typically the
+// client's request context, aborts the fetch.
+//
+// When a mirror fails, answers anything but 200 or 404, or serves a
+// Cloudflare challenge, the same path is tried on the next mirror; a 404 is
+// a real answer, not an outage. The mirror that answers becomes the first one
+// tried next time. If every mirror fails, the last response or error is
+// returned.
+//
+// Each call also feeds the circuit breaker, which fails it at once while
+// Einthusan is known to be down, and is charged to the request's scrape
+// budget (see chargeFetch).
+func fetchUpstream(ctx context.Context, url string) (*http.Response, error) {
+	return doUpstream(ctx, upstreamRequest{url: url})
+}
+
+// upstreamRequest is one call through the fetch pipeline. Most are plain page
+// GETs; the player handshake in stream.go also POSTs a form, on a client
+// with its own cookie jar, to the mirror its page came from.
+type upstreamRequest struct {
+	url    string
+	form   url.Values   // sent as a POST body when set
+	header http.Header  // set on top of the browser headers
+	client *http.Client // httpClient when nil
+	pinned bool         // stay on url's mirror rather than failing over
+}
+
+// doUpstream is fetchUpstream for any upstreamRequest, with the same
+// backoff, budget, breaker, failover and retries.
+func doUpstream(ctx context.Context, r upstreamRequest) (*http.Response, error) {
+	if until, active := backoffUntil(); active {
+		return nil, &backoffError{until: until}
+	}
+	if err := scopeFrom(ctx).chargeFetch(ctx); err != nil {
+		return nil, err
+	}
+	if err := breaker.allow(); err != nil {
+		return nil, err
+	}
+	res, err := fetchMirrors(ctx, r)
+	var backoff *backoffError
+	switch {
+	case errors.As(err, &backoff) || errors.Is(err, errUpstreamBusy) || ctx.Err() != nil:
+		breaker.release()
+	default:
+		breaker.record(err == nil && (res.StatusCode == http.StatusOK || res.StatusCode == http.StatusNotFound) && res.Header.Get("Cf-Mitigated") != "challenge")
+	}
+	return res, err
+}
+
+// fetchMirrors is doUpstream's walk through the mirrors. A pinned request
+// only tries its own.
+func fetchMirrors(ctx context.Context, r upstreamRequest) (*http.Response, error) {
+	candidates := []string{r.url}
+	if !r.pinned {
+		candidates = scraper.Einthusan.Candidates(r.url)
+	}
+	var res *http.Response
+	var err error
+	for _, candidate := range candidates {
+		if res != nil {
+			res.Body.Close()
+		}
+		r.url = candidate
+		res, err = fetchWithRetry(ctx, r)
+		var backoff *backoffError
+		if errors.As(err, &backoff) || errors.Is(err, errUpstreamBusy) || ctx.Err() != nil {
+			return nil, err
+		}
+		if err == nil && (res.StatusCode == http.StatusOK || res.StatusCode == http.StatusNotFound) && res.Header.Get("Cf-Mitigated") != "challenge" {
+			scraper.Einthusan.MarkHealthy(candidate)
+			return res, nil
+		}
+	}
+	return res, err
+}
+
+// fetchWithRetry sends r to one mirror, retrying transient failures with
+// exponential backoff. A 4xx, including a 429, is never retried, and nor is
+// a request the scheduler turned away. Cancelling
+// ctx aborts the wait between attempts. If every attempt fails, the last 5xx
+// response is returned as is, or the last error wrapped with the attempt count.
+func fetchWithRetry(ctx context.Context, r upstreamRequest) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := fetchOnce(ctx, r)
+		var backoff *backoffError
+		transient := (err != nil && !errors.As(err, &backoff) && !errors.Is(err, errUpstreamBusy)) || (err == nil && res.StatusCode >= http.StatusInternalServerError)
+		if !transient || ctx.Err() != nil {
+			return res, err
+		}
+		if attempt == upstreamAttempts {
+			if err != nil {
+				return nil, fmt.Errorf("upstream failed after %d attempts: %w", attempt, err)
+			}
+			return res, nil
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+		delay := upstreamRetryBase << (attempt - 1)
+		delay += time.Duration(rand.Int64N(int64(delay) + 1))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// fetchOnce sends r once to one mirror, once the scheduler lets it go.
+func fetchOnce(ctx context.Context, r upstreamRequest) (*http.Response, error) {
+	method, body := http.MethodGet, io.Reader(nil)
+	if r.form != nil {
+		method, body = http.MethodPost, strings.NewReader(r.form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.url, body)
+	if err != nil {
+		return nil, err
+	}
+	setBrowserHeaders(req)
+	if r.form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	res, err := scheduler.do(cmp.Or(r.client, httpClient), req)
+	if errors.Is(err, errUpstreamBusy) || ctx.Err() != nil {
+		return res, err
+	}
+	if err != nil {
+		recentErrors.add(r.url, err)
+		return nil, err
+	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		res.Body.Close()
+		until := time.Now().Add(parseRetryAfter(res.Header.Get("Retry-After")))
+		upstreamBackoff.mu.Lock()
+		if until.After(upstreamBackoff.until) {
+			upstreamBackoff.until = until
+		}
+		upstreamBackoff.mu.Unlock()
+		err := &backoffError{until: until}
+		recentErrors.add(r.url, err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// setBrowserHeaders makes req look like a page load from the primary mirror,
+// applying the request's header overrides when there are any.
+func setBrowserHeaders(req *http.Request) {
+	override, _ := req.Context().Value(overrideKey{}).(upstreamOverride)
+	req.Header.Set("User-Agent", cmp.Or(override.userAgent, userAgent))
+	req.Header.Set("Accept-Language", cmp.Or(override.acceptLanguage, acceptLanguage))
+	req.Header.Set("Referer", scraper.Einthusan.BaseURL()+"/")
+}
+
+// backoffUntil reports whether scrapes are paused, clearing the state once
+// the window has passed.
+func backoffUntil() (time.Time, bool) {
+	upstreamBackoff.mu.Lock()
+	defer upstreamBackoff.mu.Unlock()
+	if upstreamBackoff.until.IsZero() {
+		return time.Time{}, false
+	}
+	if time.Now().After(upstreamBackoff.until) {
+		upstreamBackoff.until = time.Time{}
+		return time.Time{}, false
+	}
+	return upstreamBackoff.until, true
+}
+
+// parseRetryAfter accepts both forms of Retry-After: delay seconds or an
+// HTTP date. Either is clamped to maxRetryAfter.
+func parseRetryAfter(value string) time.Duration {
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return min(time.Duration(secs)*time.Second, maxRetryAfter)
+	}
+	if at, err := http.ParseTime(value); err == nil && time.Until(at) > 0 {
+		return min(time.Until(at), maxRetryAfter)
+	}
+	return defaultRetryAfter
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestConfigureScrapeProxy(t *testing.T) {
+	transport := httpClient.Transport.(*http.Transport)
+	previous := transport.Proxy
+	t.Cleanup(func() { transport.Proxy = previous })
+
+	proxyFor := func() string {
+		t.Helper()
+		if transport.Proxy == nil {
+			return ""
+		}
+		u, err := transport.Proxy(httptest.NewRequest(http.MethodGet, "https://einthusan.tv/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u == nil {
+			return ""
+		}
+		return u.String()
+	}
+
+	t.Setenv("SCRAPE_PROXY", "socks5://proxy.example:1080")
+	if err := ConfigureScrapeProxy(Setting("SCRAPE_PROXY")); err != nil {
+		t.Fatalf("configureScrapeProxy: %v", err)
+	}
+	if got := proxyFor(); got != "socks5://proxy.example:1080" {
+		t.Errorf("scrapes go through %q, want the SOCKS proxy", got)
+	}
+
+	if err := ConfigureScrapeProxy("http://a.example:8080, http://b.example:8080"); err != nil {
+		t.Fatalf("configureScrapeProxy: %v", err)
+	}
+	if first, second, third := proxyFor(), proxyFor(), proxyFor(); first != "http://a.example:8080" || second != "http://b.example:8080" || third != first {
+		t.Errorf("rotation went %s, %s, %s", first, second, third)
+	}
+
+	for _, raw := range []string{"ftp://proxy.example", "http://"} {
+		if err := ConfigureScrapeProxy(raw); err == nil {
+			t.Errorf("configureScrapeProxy(%q) accepted an invalid proxy", raw)
+		}
+	}
+}
+
+func TestUpstream429PausesScrapes(t *testing.T) {
+	hits := fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	router := NewRouter()
+	for i := range 2 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/language/tamil", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("request %d: status = %d, want 503; body %s", i+1, w.Code, w.Body)
+		}
+		if wait, _ := strconv.Atoi(w.Header().Get("Retry-After")); wait < 110 || wait > 120 {
+			t.Errorf("request %d: Retry-After = %q, want about 120", i+1, w.Header().Get("Retry-After"))
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream hit %d times, want 1; the second request should wait out the backoff", n)
+	}
+
+	// Once the window has passed, scrapes go out again.
+	upstreamBackoff.mu.Lock()
+	upstreamBackoff.until = time.Now().Add(-time.Second)
+	upstreamBackoff.mu.Unlock()
+	if _, active := backoffUntil(); active {
+		t.Error("backoff still active after its window")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"120", 2 * time.Minute},
+		{"86400", maxRetryAfter},
+		{time.Now().Add(48 * time.Hour).UTC().Format(http.TimeFormat), maxRetryAfter},
+		{"", defaultRetryAfter},
+		{"-5", defaultRetryAfter},
+		{"soon", defaultRetryAfter},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
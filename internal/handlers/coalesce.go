@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+
+	"apppp/internal/scraper"
+)
+
+// listingFlights coalesces concurrent scrapes of the same URL: the first
+// caller fetches, and anyone asking for that URL before it finishes waits for
+// the same result instead of hitting Einthusan again.
+var listingFlights = &coalescer{flights: make(map[string]*flight)}
+
+type coalescer struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done    chan struct{}
+	result  scraper.Listing
+	err     error
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// do returns fn's result for key, running fn only if no call for key is
+// already under way; shared reports whether this caller joined another's.
+//
+// fn runs detached from any one caller's context, so a client that hangs up
+// doesn't fail the others. It is cancelled once every caller waiting on it
+// has gone, so an abandoned scrape still stops early.
+func (co *coalescer) do(ctx context.Context, key string, fn func(context.Context) (scraper.Listing, error)) (result scraper.Listing, err error, shared bool) {
+	co.mu.Lock()
+	f, shared := co.flights[key]
+	if shared {
+		f.waiters++
+	} else {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel, waiters: 1}
+		co.flights[key] = f
+		go func() {
+			defer cancel()
+			f.result, f.err = fn(fctx)
+			co.mu.Lock()
+			if co.flights[key] == f {
+				delete(co.flights, key)
+			}
+			co.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	co.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result, f.err, shared
+	case <-ctx.Done():
+		co.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			if co.flights[key] == f {
+				delete(co.flights, key)
+			}
+		}
+		co.mu.Unlock()
+		return scraper.Listing{}, ctx.Err(), shared
+	}
+}
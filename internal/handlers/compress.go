@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultCompressMinBytes = 1024
+
+// compressMinSize is the smallest body worth compressing; below it the
+// encoding overhead outweighs the savings. COMPRESS_MIN_BYTES overrides it,
+// and a negative value turns compression off.
+var compressMinSize = EnvInt("COMPRESS_MIN_BYTES", defaultCompressMinBytes)
+
+// bufferedWriter holds the response body so the middleware can decide on an
+// encoding once the handler has finished.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// compressResponses encodes responses with brotli or gzip depending on the
+// client's Accept-Encoding, preferring brotli and falling back to identity.
+// Routes in skip stream their own output and are passed through unbuffered.
+func compressResponses(skip ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(skip, c.FullPath()) {
+			c.Next()
+			return
+		}
+		original := c.Writer
+		bw := &bufferedWriter{ResponseWriter: original}
+		c.Writer = bw
+		c.Next()
+		c.Writer = original
+
+		body := bw.buf.Bytes()
+		if len(body) == 0 {
+			// Leave the writer untouched so gin can still write its default 404/405 bodies.
+			return
+		}
+		header := original.Header()
+		header.Add("Vary", "Accept-Encoding")
+
+		status := original.Status()
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || compressMinSize < 0 || len(body) < compressMinSize || header.Get("Content-Encoding") != "" ||
+			strings.HasPrefix(header.Get("Content-Type"), "image/") ||
+			c.Request.Method == http.MethodHead || status == http.StatusNoContent || status == http.StatusNotModified {
+			original.Write(body)
+			return
+		}
+
+		header.Set("Content-Encoding", encoding)
+		header.Del("Content-Length")
+		var enc io.WriteCloser
+		if encoding == "br" {
+			enc = brotli.NewWriterLevel(original, brotli.DefaultCompression)
+		} else {
+			enc = gzip.NewWriter(original)
+		}
+		enc.Write(body)
+		enc.Close()
+	}
+}
+
+// negotiateEncoding picks "br", "gzip" or "" (identity) from an
+// Accept-Encoding header, honouring q=0 exclusions.
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := acceptedEncodings(acceptEncoding)
+	switch {
+	case accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	case accepted["*"]:
+		if _, excluded := accepted["br"]; !excluded {
+			return "br"
+		}
+		if _, excluded := accepted["gzip"]; !excluded {
+			return "gzip"
+		}
+	}
+	return ""
+}
+
+// acceptsGzip reports whether the client takes gzip, for responses that
+// can't offer brotli.
+func acceptsGzip(acceptEncoding string) bool {
+	accepted := acceptedEncodings(acceptEncoding)
+	if ok, listed := accepted["gzip"]; listed {
+		return ok
+	}
+	return accepted["*"]
+}
+
+// acceptedEncodings maps each coding in an Accept-Encoding header to whether
+// it is acceptable, that is listed without q=0.
+func acceptedEncodings(acceptEncoding string) map[string]bool {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[name] = q > 0
+	}
+	return accepted
+}
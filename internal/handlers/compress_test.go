@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+func TestCompressResponses(t *testing.T) {
+	movies := make([]models.MovieEntry, 50)
+	for i := range movies {
+		movies[i] = models.MovieEntry{ID: "a" + strings.Repeat("1", i%5), Title: "Theri", Synopsis: "A policeman in hiding is drawn back into his past."}
+	}
+	want, err := json.Marshal(movies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(compressResponses())
+	r.GET("/big", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", want) })
+	small := []byte(`{"ok":true}`)
+	r.GET("/small", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", small) })
+
+	tests := []struct {
+		name, path, accept, wantEncoding string
+		decode                           func(io.Reader) (io.Reader, error)
+		wantBody                         []byte
+	}{
+		{"brotli preferred", "/big", "gzip, br", "br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }, want},
+		{"gzip", "/big", "gzip", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }, want},
+		{"identity", "/big", "", "", func(r io.Reader) (io.Reader, error) { return r, nil }, want},
+		{"too small to compress", "/small", "br", "", func(r io.Reader) (io.Reader, error) { return r, nil }, small},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", tt.accept)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			reader, err := tt.decode(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			if !bytes.Equal(body, tt.wantBody) {
+				t.Errorf("decoded body differs from the original (%d bytes, want %d)", len(body), len(tt.wantBody))
+			}
+		})
+	}
+}
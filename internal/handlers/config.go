@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/config"
+)
+
+// Settings are read through the config package: the environment first, then
+// CONFIG_FILE. knownSettings lists every one of them, so startup can reject
+// a typo or a bad value and the config routes can show what is in effect.
+
+var knownSettings = []config.Setting{
+	{Name: "PORT", Default: "8080", Kind: config.Int, Check: config.Between(1, 65535)},
+	{Name: "LOG_LEVEL", Default: "info", Check: config.OneOf("debug", "info", "warn", "error")},
+	{Name: "DEBUG", Default: "false", Kind: config.Bool},
+	{Name: "DEFAULT_LANGUAGE", Check: languages},
+	{Name: "EINTHUSAN_BASE_URL", Check: config.URLs},
+	{Name: "SCRAPE_PROXY", Secret: true},
+	{Name: "UPSTREAM_USER_AGENT", Default: defaultUserAgent},
+	{Name: "UPSTREAM_ACCEPT_LANGUAGE", Default: defaultAcceptLanguage},
+	{Name: "UPSTREAM_HEADER_OVERRIDE", Default: "false", Kind: config.Bool},
+	{Name: "UPSTREAM_CONNECT_TIMEOUT_MS", Default: strconv.Itoa(defaultUpstreamConnectTimeoutMs), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "UPSTREAM_READ_TIMEOUT_MS", Default: strconv.Itoa(defaultUpstreamReadTimeoutMs), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "UPSTREAM_IDLE_CONNS", Default: strconv.Itoa(defaultUpstreamIdleConns), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "UPSTREAM_ATTEMPTS", Default: strconv.Itoa(defaultUpstreamAttempts), Kind: config.Int, Check: config.AtLeast(1)},
+	{Name: "UPSTREAM_RETRY_BASE_MS", Default: strconv.Itoa(defaultUpstreamRetryBaseMs), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "UPSTREAM_CONCURRENCY", Default: strconv.Itoa(defaultUpstreamConcurrency), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "UPSTREAM_MIN_DELAY_MS", Default: strconv.Itoa(defaultUpstreamMinDelayMs), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "UPSTREAM_JITTER_MS", Default: strconv.Itoa(defaultUpstreamJitterMs), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "UPSTREAM_QUEUE_TIMEOUT_MS", Default: strconv.Itoa(defaultUpstreamQueueTimeoutMs), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "BREAKER_FAILURES", Default: strconv.Itoa(defaultBreakerFailures), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "BREAKER_COOLDOWN_SECONDS", Default: strconv.Itoa(defaultBreakerCooldownSeconds), Kind: config.Int, Check: config.AtLeast(1)},
+	{Name: "CACHE_TTL_SECONDS", Default: strconv.Itoa(defaultCacheTTLSeconds), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "CACHE_TTL_SEARCH_SECONDS", Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "CACHE_TTL_ACTOR_SECONDS", Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "CACHE_TTL_BROWSE_SECONDS", Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "CACHE_MAX_ENTRIES", Default: strconv.Itoa(defaultCacheMaxEntries), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "NEGATIVE_CACHE_TTL_SECONDS", Default: strconv.Itoa(defaultNegativeCacheTTLSeconds), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "CACHE_DIR"},
+	{Name: "CACHE_MAX_AGE"},
+	{Name: "CACHE_MAX_AGE_SECONDS", Default: strconv.Itoa(defaultMaxAgeSeconds), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "ACTOR_NAME_TTL_HOURS", Default: strconv.Itoa(defaultActorNameTTLHours), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "READY_CHECK_TTL_SECONDS", Default: strconv.Itoa(defaultReadyCheckTTLSeconds), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "COMPRESS_MIN_BYTES", Default: strconv.Itoa(defaultCompressMinBytes), Kind: config.Int},
+	{Name: "PREWARM_LANGUAGES", Check: languages},
+	{Name: "PREWARM_PAGES", Default: strconv.Itoa(defaultPrewarmPages), Kind: config.Int, Check: config.AtLeast(1)},
+	{Name: "PREWARM_INTERVAL_SECONDS", Kind: config.Int, Check: config.AtLeast(1)},
+	{Name: "SCRAPE_RATE_LIMIT", Default: strconv.Itoa(defaultScrapeRate), Kind: config.Float},
+	{Name: "SCRAPE_RATE_BURST", Default: strconv.Itoa(defaultScrapeBurst), Kind: config.Int},
+	{Name: "CLIENT_RATE_LIMIT", Default: strconv.Itoa(defaultClientRatePerMinute), Kind: config.Float},
+	{Name: "CLIENT_RATE_BURST", Default: strconv.Itoa(defaultClientBurst), Kind: config.Int},
+	{Name: "RATE_LIMIT_ALLOWLIST"},
+	{Name: "SEARCH_RANKING", Default: "levenshtein", Check: config.OneOf("levenshtein", "token")},
+	{Name: "TITLE_SIMILARITY", Default: strconv.FormatFloat(defaultTitleSimilarity, 'g', -1, 64), Kind: config.Float},
+	{Name: "SYNONYMS_FILE"},
+	{Name: "SELECTORS"},
+	{Name: "SELECTORS_FILE"},
+	{Name: "API_KEYS", Secret: true},
+	{Name: "API_KEYS_FILE"},
+	{Name: "API_KEY_DAILY_QUOTA", Default: strconv.Itoa(defaultAPIKeyDailyQuota), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "ADMIN_API_KEY", Secret: true},
+	{Name: "TMDB_API_KEY", Secret: true},
+	{Name: "TMDB_API_URL", Default: defaultTMDBApiUrl, Check: config.URLs},
+	{Name: "INDEX_DB"},
+	{Name: "WATCHLIST_DB"},
+	{Name: "DOWNLOAD_DIR"},
+	{Name: "DOWNLOAD_WORKERS", Default: strconv.Itoa(DefaultDownloadWorkers), Kind: config.Int, Check: config.AtLeast(1)},
+	{Name: "CORS_ALLOWED_ORIGINS"},
+	{Name: "CORS_ALLOWED_METHODS"},
+	{Name: "CORS_ALLOWED_HEADERS"},
+	{Name: "SERVER_READ_TIMEOUT_SECONDS", Default: strconv.Itoa(defaultReadTimeoutSeconds), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "SERVER_WRITE_TIMEOUT_SECONDS", Default: strconv.Itoa(defaultWriteTimeoutSeconds), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "SERVER_IDLE_TIMEOUT_SECONDS", Default: strconv.Itoa(defaultIdleTimeoutSeconds), Kind: config.Int, Check: config.AtLeast(0)},
+	{Name: "SHUTDOWN_GRACE_SECONDS", Default: strconv.Itoa(defaultShutdownGraceSeconds), Kind: config.Int, Check: config.AtLeast(0)},
+}
+
+// Setting returns key's value from the environment or CONFIG_FILE.
+func Setting(key string) string {
+	return config.Get(key)
+}
+
+// ValidateConfig checks CONFIG_FILE and every known setting that is set.
+func ValidateConfig() error {
+	return config.Validate(knownSettings)
+}
+
+// languages accepts a comma-separated list of supported languages, or "all".
+func languages(raw string) error {
+	if strings.EqualFold(strings.TrimSpace(raw), "all") {
+		return nil
+	}
+	for _, part := range strings.Split(raw, ",") {
+		if language := strings.ToLower(strings.TrimSpace(part)); language != "" && !slices.Contains(supportedLanguages, language) {
+			return fmt.Errorf("%q is not a supported language", part)
+		}
+	}
+	return nil
+}
+
+type ConfigResponse struct {
+	File     string         `json:"file,omitempty"` // CONFIG_FILE, when one is loaded
+	Settings []config.Value `json:"settings"`
+}
+
+// showConfig answers GET /config, and /admin/config where it started out,
+// with the effective value of every setting, secrets redacted.
+func showConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, ConfigResponse{File: config.File(), Settings: config.Effective(knownSettings)})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gin-contrib/cors"
+)
+
+// Methods and headers allowed when CORS_ALLOWED_METHODS and
+// CORS_ALLOWED_HEADERS are unset.
+var (
+	defaultCorsMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	defaultCorsHeaders = []string{"Origin", "Content-Type", "Accept", "Accept-Encoding", "Authorization", "X-API-Key", "X-Request-ID"}
+)
+
+// corsConfig builds the CORS policy from CORS_ALLOWED_ORIGINS, a
+// comma-separated origin list. Empty or "*" allows any origin; browsers
+// refuse credentials with a wildcard, so they are only allowed for an
+// explicit list. CORS_ALLOWED_METHODS and CORS_ALLOWED_HEADERS replace the
+// default method and request header lists.
+//
+// The middleware is installed on the engine, so it also runs for unrouted
+// requests and answers the OPTIONS preflight of every route.
+func corsConfig(allowedOrigins, allowedMethods, allowedHeaders string) cors.Config {
+	config := cors.Config{
+		AllowMethods:  defaultCorsMethods,
+		AllowHeaders:  defaultCorsHeaders,
+		ExposeHeaders: []string{"Content-Length", "Retry-After", "X-Cache", "X-Request-ID"},
+	}
+	if methods := splitList(allowedMethods); len(methods) > 0 {
+		for i := range methods {
+			methods[i] = strings.ToUpper(methods[i])
+		}
+		config.AllowMethods = methods
+	}
+	if headers := splitList(allowedHeaders); len(headers) > 0 {
+		config.AllowHeaders = headers
+	}
+	var origins []string
+	for _, origin := range strings.Split(allowedOrigins, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 || slices.Contains(origins, "*") {
+		config.AllowAllOrigins = true
+		return config
+	}
+	config.AllowOrigins = origins
+	config.AllowCredentials = true
+	return config
+}
+
+// splitList splits a comma-separated setting, dropping blank entries.
+func splitList(raw string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
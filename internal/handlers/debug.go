@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrapeErrorBufferSize is how many recent upstream failures /debug/errors keeps.
+const scrapeErrorBufferSize = 50
+
+type ScrapeError struct {
+	Time  time.Time `json:"time"`
+	URL   string    `json:"url"`
+	Error string    `json:"error"`
+}
+
+// errorRing is a fixed-size, concurrency-safe buffer of the latest errors.
+type errorRing struct {
+	mu      sync.Mutex
+	entries []ScrapeError
+	next    int
+	full    bool
+}
+
+var recentErrors = &errorRing{entries: make([]ScrapeError, scrapeErrorBufferSize)}
+
+func (r *errorRing) add(url string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = ScrapeError{Time: time.Now(), URL: url, Error: err.Error()}
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the buffered errors, newest first.
+func (r *errorRing) list() []ScrapeError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.next
+	if r.full {
+		n = len(r.entries)
+	}
+	out := make([]ScrapeError, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return out
+}
+
+func debugErrors(c *gin.Context) {
+	respond(c, http.StatusOK, gin.H{"errors": recentErrors.list()})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// ScrapeMovieDetail reads the watch page for id. It returns scraper.ErrNotFound when
+// the upstream 404s or the page has no movie summary block.
+func scrapeMovieDetail(ctx context.Context, language, id string) (*models.MovieDetail, error) {
+	key := language + "/" + id
+	if isKnownMissing("movie", key) {
+		return nil, scraper.ErrNotFound
+	}
+	res, err := fetchUpstream(ctx, watchUrl(language, id))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		markMissing("movie", key)
+		return nil, scraper.ErrNotFound
+	}
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlocked(res, doc); err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, scraper.UnexpectedStatus("upstream", res)
+	}
+
+	detail, ok := scraper.ParseMovieDetail(doc, language, id)
+	if !ok {
+		markMissing("movie", key)
+		return nil, scraper.ErrNotFound
+	}
+
+	// A payload this build can't decode is left out without a warning, which
+	// would otherwise be on every movie.
+	switch streamUrl, err := extractStreamUrl(doc); {
+	case err == nil:
+		detail.StreamUrl = streamUrl
+	case !errors.Is(err, errStreamingDisabled):
+		detail.Warnings = append(detail.Warnings, "stream_url: "+err.Error())
+	}
+	return detail, nil
+}
+
+// showMovie answers /movie/:language/:id with the movie's detail page, or
+// with an NFO file of it when the ID ends in .nfo.
+func showMovie(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	source, ok := requireProvider(c, language)
+	if !ok {
+		return
+	}
+	id, nfo := strings.CutSuffix(c.Param("id"), ".nfo")
+	detail, err := source.details(c.Request.Context(), language, id)
+	if errors.Is(err, scraper.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+		return
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	if nfo {
+		writeNFO(c, detail)
+		return
+	}
+	respond(c, http.StatusOK, detail)
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const DefaultDownloadWorkers = 2
+
+// Download states. queued and downloading are live; the rest are final.
+const (
+	downloadQueued      = "queued"
+	downloadDownloading = "downloading"
+	downloadDone        = "done"
+	downloadFailed      = "failed"
+	downloadCancelled   = "cancelled"
+)
+
+// Download is one movie being saved to DOWNLOAD_DIR.
+type Download struct {
+	ID         string    `json:"id"`
+	MovieID    string    `json:"movie_id"`
+	Language   string    `json:"language"`
+	Quality    string    `json:"quality"` // "hd" or "sd"; asked for, then as found
+	Status     string    `json:"status"`
+	File       string    `json:"file,omitempty"` // Name within DOWNLOAD_DIR, once known
+	BytesDone  int64     `json:"bytes_done"`
+	BytesTotal int64     `json:"bytes_total,omitempty"` // 0 while unknown
+	Progress   float64   `json:"progress,omitempty"`    // 0 to 1, when bytes_total is known
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
+type DownloadsResponse struct {
+	Downloads []Download `json:"downloads"`
+	Count     int        `json:"count"`
+}
+
+// DownloadRequest is POST /downloads' body. id may also be a watch page URL,
+// as with /watch; quality defaults to the best available.
+type DownloadRequest struct {
+	ID       string `json:"id"`
+	Language string `json:"language"`
+	Quality  string `json:"quality"`
+}
+
+// downloadJob is a Download plus what the worker needs to run and stop it.
+type downloadJob struct {
+	Download
+	requested string       // Quality as asked for, before fetch fills in what was found
+	done      atomic.Int64 // bytes written, read without the queue's lock
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// downloadQueue saves movies server-side with a fixed pool of workers, so
+// the API can act as a small personal PVR. DOWNLOAD_DIR enables it and
+// DOWNLOAD_WORKERS sets how many movies download at once. The queue itself
+// is kept in memory: a restart forgets it and drops unfinished downloads,
+// though finished files stay in the directory.
+type downloadQueue struct {
+	dir     string
+	mu      sync.Mutex
+	jobs    map[string]*downloadJob
+	pending chan *downloadJob
+	ctx     context.Context // cancelled on shutdown, stopping every job
+	stop    context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// downloads is nil unless DOWNLOAD_DIR is set; the routes then answer 501.
+var downloads *downloadQueue
+
+// maxQueuedDownloads bounds the queue, so a runaway client can't grow it forever.
+const maxQueuedDownloads = 100
+
+// StartDownloads creates dir if needed and starts the workers. An empty dir
+// leaves downloads disabled.
+func StartDownloads(dir string, workers int) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("downloads: %w", err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	downloads = &downloadQueue{dir: dir, jobs: make(map[string]*downloadJob), pending: make(chan *downloadJob, maxQueuedDownloads), ctx: ctx, stop: stop}
+	for range max(workers, 1) {
+		downloads.workers.Go(downloads.work)
+	}
+	return nil
+}
+
+// close cancels every queued and running download and waits for the
+// workers to remove their partial files.
+func (q *downloadQueue) close() {
+	if q != nil {
+		q.stop()
+		q.workers.Wait()
+	}
+}
+
+// requireDownloads answers 501 on every download route when DOWNLOAD_DIR is
+// unset or the binary can't resolve streams.
+func requireDownloads() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !streamingEnabled {
+			respondError(c, http.StatusNotImplemented, "streaming_disabled", errStreamingDisabled.Error())
+			return
+		}
+		if downloads == nil {
+			respondError(c, http.StatusNotImplemented, "downloads_disabled", "downloads are not enabled (set DOWNLOAD_DIR)")
+			return
+		}
+		c.Next()
+	}
+}
+
+// queueDownload adds a movie to the queue and answers 202 with its Download,
+// whose id is what GET and DELETE /downloads/:id take. The stream is
+// resolved when a worker picks it up, so a bad movie ID only shows as a
+// failed download. Asking again for a movie and quality that is still
+// queued or downloading answers 409 with the existing download's id.
+func queueDownload(c *gin.Context) {
+	var req DownloadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_body", "body must be a JSON object with id, language and quality")
+		return
+	}
+	language, id, err := movieTarget(req.ID, strings.ToLower(strings.TrimSpace(req.Language)))
+	if err != nil {
+		respondError(c, http.StatusUnprocessableEntity, "invalid_body", err.Error())
+		return
+	}
+	quality := strings.ToLower(strings.TrimSpace(req.Quality))
+	if quality != "" && quality != "hd" && quality != "sd" {
+		respondError(c, http.StatusUnprocessableEntity, "invalid_body", "quality must be hd or sd")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(downloads.ctx)
+	job := &downloadJob{Download: Download{ID: newRequestID(), MovieID: id, Language: language, Quality: quality, Status: downloadQueued, CreatedAt: time.Now().UTC()}, requested: quality, ctx: ctx, cancel: cancel}
+	downloads.mu.Lock()
+	if existing := downloads.live(id, language, quality); existing != nil {
+		downloads.mu.Unlock()
+		cancel()
+		body := errorBody("download_exists", "this movie is already queued or downloading")
+		body["id"] = existing.ID
+		abortWithError(c, http.StatusConflict, body)
+		return
+	}
+	select {
+	case downloads.pending <- job:
+		downloads.jobs[job.ID] = job
+	default:
+		downloads.mu.Unlock()
+		cancel()
+		respondError(c, http.StatusServiceUnavailable, "queue_full", fmt.Sprintf("%d downloads are already queued", maxQueuedDownloads))
+		return
+	}
+	snapshot := job.snapshot()
+	downloads.mu.Unlock()
+	respond(c, http.StatusAccepted, snapshot)
+}
+
+// live returns the queued or running job for the same movie and requested
+// quality, if there is one. Callers hold the queue's lock.
+func (q *downloadQueue) live(movieID, language, quality string) *downloadJob {
+	for _, job := range q.jobs {
+		if job.MovieID == movieID && job.Language == language && job.requested == quality &&
+			(job.Status == downloadQueued || job.Status == downloadDownloading) {
+			return job
+		}
+	}
+	return nil
+}
+
+// listDownloads returns every download the queue knows of, newest first.
+func listDownloads(c *gin.Context) {
+	resp := DownloadsResponse{Downloads: []Download{}}
+	downloads.mu.Lock()
+	for _, job := range downloads.jobs {
+		resp.Downloads = append(resp.Downloads, job.snapshot())
+	}
+	downloads.mu.Unlock()
+	slices.SortFunc(resp.Downloads, func(a, b Download) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	resp.Count = len(resp.Downloads)
+	respond(c, http.StatusOK, resp)
+}
+
+func showDownload(c *gin.Context) {
+	downloads.mu.Lock()
+	job, ok := downloads.jobs[c.Param("id")]
+	var snapshot Download
+	if ok {
+		snapshot = job.snapshot()
+	}
+	downloads.mu.Unlock()
+	if !ok {
+		respondError(c, http.StatusNotFound, "download_not_found", "download not found")
+		return
+	}
+	respond(c, http.StatusOK, snapshot)
+}
+
+// cancelDownload stops a queued or running download and deletes its partial
+// file. A finished one is only dropped from the list; its file is kept. A
+// running download shows as cancelled once its worker has stopped.
+func cancelDownload(c *gin.Context) {
+	downloads.mu.Lock()
+	job, ok := downloads.jobs[c.Param("id")]
+	if ok {
+		switch job.Status {
+		case downloadQueued:
+			job.cancel()
+			job.Status, job.FinishedAt = downloadCancelled, time.Now().UTC()
+		case downloadDownloading:
+			job.cancel()
+		default:
+			delete(downloads.jobs, job.ID)
+		}
+	}
+	downloads.mu.Unlock()
+	if !ok {
+		respondError(c, http.StatusNotFound, "download_not_found", "download not found")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// snapshot copies the job's public state. Callers hold the queue's lock.
+func (job *downloadJob) snapshot() Download {
+	d := job.Download
+	d.BytesDone = job.done.Load()
+	if d.BytesTotal > 0 {
+		d.Progress = min(float64(d.BytesDone)/float64(d.BytesTotal), 1)
+	}
+	return d
+}
+
+// update changes the job's state under the queue's lock.
+func (q *downloadQueue) update(job *downloadJob, change func(d *Download)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change(&job.Download)
+}
+
+func (q *downloadQueue) work() {
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case job := <-q.pending:
+			q.run(job)
+		}
+	}
+}
+
+// run downloads one job, recording how it ended.
+func (q *downloadQueue) run(job *downloadJob) {
+	defer job.cancel()
+	if job.ctx.Err() != nil {
+		return // Cancelled while queued; cancelDownload recorded it
+	}
+	q.update(job, func(d *Download) { d.Status, d.StartedAt = downloadDownloading, time.Now().UTC() })
+	err := q.fetch(job)
+	q.update(job, func(d *Download) {
+		d.FinishedAt = time.Now().UTC()
+		switch {
+		case job.ctx.Err() != nil:
+			d.Status = downloadCancelled
+		case err != nil:
+			d.Status, d.Error = downloadFailed, err.Error()
+		default:
+			d.Status = downloadDone
+		}
+	})
+	if err != nil && job.ctx.Err() == nil {
+		slog.Warn("download failed", "download", job.ID, "movie", job.MovieID, "language", job.Language, "error", err)
+	}
+}
+
+// fetch resolves the job's stream and writes it to DOWNLOAD_DIR, first as a
+// .part file that is renamed once complete and removed if it isn't. The
+// .part name carries the job's ID, so two jobs that resolve to the same file,
+// such as one for the best quality and one for hd, never write to it at once.
+func (q *downloadQueue) fetch(job *downloadJob) error {
+	streams, err := resolveStreams(job.ctx, job.Language, job.MovieID)
+	if err != nil {
+		return err
+	}
+	source, ok := pickDownloadSource(streams.Sources, job.Quality)
+	if !ok {
+		if job.Quality != "" && slices.ContainsFunc(streams.Sources, func(s StreamSource) bool { return s.Type == "mp4" }) {
+			return fmt.Errorf("no %s MP4 stream for this movie", job.Quality)
+		}
+		return errors.New("no MP4 stream for this movie; HLS-only movies can't be downloaded")
+	}
+	name := fmt.Sprintf("%s-%s-%s.mp4", job.Language, filepath.Base(job.MovieID), source.Quality)
+	q.update(job, func(d *Download) { d.Quality, d.File = source.Quality, name })
+
+	req, err := http.NewRequestWithContext(job.ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return err
+	}
+	setBrowserHeaders(req)
+	// Not httpClient: its timeout covers the whole body, and a movie takes
+	// far longer than a page to arrive.
+	res, err := (&http.Client{Transport: httpClient.Transport}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("video host returned %s", res.Status)
+	}
+	if res.ContentLength > 0 {
+		q.update(job, func(d *Download) { d.BytesTotal = res.ContentLength })
+	}
+
+	path := filepath.Join(q.dir, name)
+	part := path + "." + job.ID + ".part"
+	file, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, &countingReader{r: res.Body, n: &job.done})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(part, path)
+	}
+	if err != nil {
+		os.Remove(part)
+	}
+	return err
+}
+
+// pickDownloadSource returns the MP4 source of the given quality, or the
+// best one when quality is empty. HLS sources are skipped.
+func pickDownloadSource(sources []StreamSource, quality string) (StreamSource, bool) {
+	var best StreamSource
+	found := false
+	for _, s := range sources {
+		if s.Type != "mp4" {
+			continue
+		}
+		if s.Quality == quality {
+			return s, true
+		}
+		if quality == "" && (!found || s.Quality == "hd") {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// countingReader adds the bytes read through it to n.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n.Add(int64(n))
+	return n, err
+}
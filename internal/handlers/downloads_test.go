@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestQueueDownloadRejectsLiveDuplicate(t *testing.T) {
+	// No workers, so queued jobs stay queued.
+	ctx, stop := context.WithCancel(context.Background())
+	previous := downloads
+	downloads = &downloadQueue{dir: t.TempDir(), jobs: make(map[string]*downloadJob), pending: make(chan *downloadJob, maxQueuedDownloads), ctx: ctx, stop: stop}
+	t.Cleanup(func() {
+		stop()
+		downloads = previous
+	})
+
+	post := func(body string) (int, map[string]any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/downloads", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		queueDownload(c)
+		var resp map[string]any
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, first := post(`{"id": "a1", "language": "tamil", "quality": "hd"}`)
+	if code != http.StatusAccepted {
+		t.Fatalf("first request: status = %d", code)
+	}
+	code, dup := post(`{"id": "a1", "language": "Tamil", "quality": "HD"}`)
+	if code != http.StatusConflict || dup["id"] != first["id"] {
+		t.Errorf("duplicate: status = %d, id %v; want 409 naming %v", code, dup["id"], first["id"])
+	}
+	if code, _ := post(`{"id": "a1", "language": "tamil", "quality": "sd"}`); code != http.StatusAccepted {
+		t.Errorf("other quality: status = %d, want 202", code)
+	}
+
+	// Once the first is no longer live, the movie can be queued again.
+	downloads.mu.Lock()
+	downloads.jobs[first["id"].(string)].Status = downloadFailed
+	downloads.mu.Unlock()
+	if code, _ := post(`{"id": "a1", "language": "tamil", "quality": "hd"}`); code != http.StatusAccepted {
+		t.Errorf("after the first failed: status = %d, want 202", code)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+// maxConcurrentEnrich bounds the detail page fetches one enriched listing
+// makes at once.
+const maxConcurrentEnrich = 4
+
+// enrichTTL is how long a movie's trailer and poster are remembered. They
+// rarely change, and re-fetching a detail page per listed movie is costly.
+const enrichTTL = 24 * time.Hour
+
+type enrichment struct {
+	trailer, posterHD string
+}
+
+var enrichCache = newTTLCache[enrichment](enrichTTL)
+
+// wantsFullFields reads fields=, which is "basic" (the default) or "full".
+// Full keeps the duration, synopsis and views read from the listing; basic
+// drops them to keep responses small. A list of fields counts as full. It
+// responds 400 for anything else.
+func wantsFullFields(c *gin.Context) (bool, bool) {
+	switch c.DefaultQuery("fields", "basic") {
+	case "basic":
+		return false, true
+	case "full":
+		return true, true
+	}
+	if projection(c) != nil {
+		return true, true // projectFields has checked it, and respond trims to it
+	}
+	respondError(c, http.StatusBadRequest, "invalid_parameter", "fields must be basic or full")
+	return false, false
+}
+
+// basicFields clears the listing fields only fields=full returns.
+func basicFields(movies []models.MovieEntry) {
+	for i := range movies {
+		movies[i].Duration, movies[i].Synopsis, movies[i].Views = "", "", 0
+	}
+}
+
+// wantsEnrich reads the enrich= flag, responding 400 when it isn't a boolean.
+func wantsEnrich(c *gin.Context) (bool, bool) {
+	raw := c.Query("enrich")
+	if raw == "" {
+		return false, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "enrich must be true or false")
+		return false, false
+	}
+	return v, true
+}
+
+// enrichMovies fills in the trailer and HD poster of each movie from its
+// detail page, a few at a time. A movie whose page can't be read is left as
+// it is; enrichment never fails the listing.
+func enrichMovies(ctx context.Context, language string, movies []models.MovieEntry) {
+	sem := make(chan struct{}, maxConcurrentEnrich)
+	var wg sync.WaitGroup
+	for i := range movies {
+		if movies[i].ID == "" {
+			continue
+		}
+		wg.Go(func() {
+			key := language + "/" + movies[i].ID
+			e, ok := enrichCache.get(key)
+			if !ok {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				detail, err := upstream.ScrapeMovieDetail(ctx, language, movies[i].ID)
+				<-sem
+				if err != nil {
+					return
+				}
+				e = enrichment{trailer: detail.Trailer, posterHD: detail.PosterHD}
+				enrichCache.set(key, e)
+			}
+			movies[i].Trailer, movies[i].PosterHD = e.trailer, e.posterHD
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+)
+
+// envFloat reads a float setting, falling back to def when unset or invalid.
+func envFloat(key string, def float64) float64 {
+	raw := Setting(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Printf("config: ignoring invalid %s=%q: %v", key, raw, err)
+		return def
+	}
+	return v
+}
+
+// EnvInt reads an integer setting, falling back to def when unset or invalid.
+func EnvInt(key string, def int) int {
+	raw := Setting(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("config: ignoring invalid %s=%q: %v", key, raw, err)
+		return def
+	}
+	return v
+}
+
+// envBool reads a boolean setting such as DEBUG=1 or DEBUG=true.
+func envBool(key string, def bool) bool {
+	raw := Setting(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Printf("config: ignoring invalid %s=%q: %v", key, raw, err)
+		return def
+	}
+	return v
+}
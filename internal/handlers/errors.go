@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+// errorBody is the shape of every error response: a stable,
+// machine-readable code and a human-readable message. error repeats the
+// message for clients written before message existed. Callers may add
+// fields to it; abortWithError adds the request ID.
+func errorBody(code, message string) gin.H {
+	return gin.H{"code": code, "message": message, "error": message}
+}
+
+// respondError writes an error response and stops the handler chain.
+func respondError(c *gin.Context, status int, code, message string) {
+	abortWithError(c, status, errorBody(code, message))
+}
+
+// abortWithError writes body, built by errorBody, as the response and stops
+// the handler chain. It adds request_id, so a client's report can be matched
+// to the server's logs.
+func abortWithError(c *gin.Context, status int, body gin.H) {
+	if id := requestID(c.Request.Context()); id != "" {
+		body["request_id"] = id
+	}
+	c.AbortWithStatusJSON(status, body)
+}
+
+// respondInternalError answers 500 for a failure of our own, such as
+// storage. err is logged rather than sent to the client.
+func respondInternalError(c *gin.Context, code string, err error) {
+	slog.Error("request failed", "request_id", requestID(c.Request.Context()), "route", c.FullPath(), "code", code, "error", err)
+	respondError(c, http.StatusInternalServerError, code, "internal error")
+}
+
+// respondScrapeError maps a failed scrape to a response: 503 with the
+// remaining wait while the upstream has us backing off or the circuit
+// breaker is open, 451 when it serves a geo-block page, 504 when it didn't
+// answer in time, 502 otherwise. A block page's kind is passed on in reason,
+// and an unusable upstream status in upstream_status.
+func respondScrapeError(c *gin.Context, err error) {
+	if errors.Is(err, context.Canceled) && c.Request.Context().Err() != nil {
+		// The client hung up, which is what cancelled the scrape; there is
+		// no one to answer. 499 is nginx's status for this, kept for metrics.
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
+	status, code, message := classifyScrapeError(err)
+	body := errorBody(code, message)
+	if upstream := upstreamStatus(err); upstream != 0 {
+		body["upstream_status"] = upstream
+	}
+	var blocked *scraper.BlockedError
+	if errors.As(err, &blocked) {
+		body["reason"] = blocked.Reason
+	}
+	var backoff *backoffError
+	if errors.As(err, &backoff) {
+		wait := int(math.Ceil(time.Until(backoff.until).Seconds()))
+		c.Header("Retry-After", strconv.Itoa(wait))
+		body["retry_after"] = wait
+	}
+	if code == "upstream_error" {
+		slog.Warn("upstream request failed", "request_id", requestID(c.Request.Context()), "route", c.FullPath(), "error", err)
+	}
+	abortWithError(c, status, body)
+}
+
+// statusClientClosedRequest is recorded for requests whose client went away
+// before the upstream answered.
+const statusClientClosedRequest = 499
+
+// classifyScrapeError is the status, error code and message
+// respondScrapeError would answer err with, for callers that report errors
+// inside a response rather than as one. Unrecognised errors get a generic
+// message; their details are only logged.
+func classifyScrapeError(err error) (status int, code, message string) {
+	var backoff *backoffError
+	if errors.As(err, &backoff) && backoff.circuit {
+		return http.StatusServiceUnavailable, "upstream_unavailable", "upstream is failing, retry later"
+	}
+	if errors.As(err, &backoff) {
+		return http.StatusServiceUnavailable, "upstream_rate_limited", "upstream is rate limiting, retry later"
+	}
+	if errors.Is(err, errUpstreamBusy) {
+		return http.StatusServiceUnavailable, "upstream_busy", "too many upstream requests queued, retry later"
+	}
+	var blocked *scraper.BlockedError
+	if errors.As(err, &blocked) && blocked.Reason == scraper.BlockGeo {
+		return http.StatusUnavailableForLegalReasons, "upstream_blocked", err.Error()
+	}
+	if errors.As(err, &blocked) {
+		return http.StatusBadGateway, "upstream_blocked", err.Error()
+	}
+	if errors.Is(err, scraper.ErrParseFailed) {
+		return http.StatusBadGateway, "upstream_parse_failed", scraper.ErrParseFailed.Error()
+	}
+	if errors.Is(err, scraper.ErrUnexpectedPage) {
+		return http.StatusBadGateway, "upstream_unexpected_page", err.Error()
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout, "upstream_timeout", "upstream timeout"
+	}
+	return http.StatusBadGateway, "upstream_error", "upstream request failed"
+}
+
+// upstreamStatus is the HTTP status behind a failed scrape, or 0 when the
+// upstream never answered with one.
+func upstreamStatus(err error) int {
+	var statusErr *scraper.UpstreamStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status
+	}
+	var backoff *backoffError
+	if errors.As(err, &backoff) && !backoff.circuit {
+		return http.StatusTooManyRequests
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultMaxAgeSeconds = 60
+
+// revalidatedRoutes report live state, so clients may keep a copy but must
+// check it with If-None-Match before every use.
+var revalidatedRoutes = []string{"/health", "/healthz", "/readyz", "/stats", "/metrics", "/usage", "/watchlist", "/downloads", "/downloads/:id", "/debug/errors"}
+
+// privateRoutes are the admin reads. No cache may store them, whatever
+// CACHE_MAX_AGE says.
+var privateRoutes = []string{"/admin/cache", "/admin/breaker", "/admin/errors", "/admin/config", "/config"}
+
+// maxAges is the Cache-Control max-age of each route, keyed by the route's
+// pattern as registered, e.g. "/search/:language".
+type maxAges struct {
+	fallback int
+	routes   map[string]int
+}
+
+// loadMaxAges reads CACHE_MAX_AGE_SECONDS, the max-age of every cacheable
+// route, and CACHE_MAX_AGE, comma-separated route=seconds overrides such as
+// "/movie/:language/:id=3600,/search/:language=30". A max-age of 0 means
+// no-cache.
+func loadMaxAges(raw string) maxAges {
+	ages := maxAges{fallback: EnvInt("CACHE_MAX_AGE_SECONDS", defaultMaxAgeSeconds), routes: make(map[string]int)}
+	if ages.fallback < 0 {
+		log.Printf("config: ignoring CACHE_MAX_AGE_SECONDS, must not be negative")
+		ages.fallback = defaultMaxAgeSeconds
+	}
+	for _, route := range revalidatedRoutes {
+		ages.routes[route] = 0
+	}
+	for _, part := range splitList(raw) {
+		route, rawAge, _ := strings.Cut(part, "=")
+		age, err := strconv.Atoi(strings.TrimSpace(rawAge))
+		if err != nil || age < 0 || !strings.HasPrefix(route, "/") {
+			log.Printf("config: ignoring CACHE_MAX_AGE entry %q, want /route=seconds", part)
+			continue
+		}
+		ages.routes[strings.TrimSpace(route)] = age
+	}
+	return ages
+}
+
+func (ma maxAges) forRoute(route string) int {
+	if age, ok := ma.routes[route]; ok {
+		return age
+	}
+	return ma.fallback
+}
+
+// cacheControl is the header value for route. Responses are private when
+// API keys are on or the request was authorized, so a shared cache can't
+// serve them to unauthenticated clients.
+func (ma maxAges) cacheControl(route string, authorized bool) string {
+	if slices.Contains(privateRoutes, route) {
+		return "private, no-store"
+	}
+	scope := "public"
+	if apiKeys.enabled() || authorized {
+		scope = "private"
+	}
+	if age := ma.forRoute(route); age > 0 {
+		return fmt.Sprintf("%s, max-age=%d", scope, age)
+	}
+	return scope + ", no-cache"
+}
+
+// conditionalResponses tags successful GET responses with a strong ETag,
+// the hash of the body, and answers 304 Not Modified when If-None-Match
+// already holds it. Responses that don't set their own Cache-Control get
+// one from ages. Routes in skip stream their own output and are passed
+// through unbuffered.
+//
+// It runs inside compressResponses, so the tag is of the uncompressed body
+// and is the same for every Content-Encoding.
+func conditionalResponses(ages maxAges, skip ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || slices.Contains(skip, c.FullPath()) {
+			c.Next()
+			return
+		}
+		original := c.Writer
+		bw := &bufferedWriter{ResponseWriter: original}
+		c.Writer = bw
+		c.Next()
+		c.Writer = original
+
+		body := bw.buf.Bytes()
+		if len(body) == 0 {
+			// Leave the writer untouched so gin can still write its default 404/405 bodies.
+			return
+		}
+		if original.Status() != http.StatusOK {
+			original.Write(body)
+			return
+		}
+		header := original.Header()
+		if header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", ages.cacheControl(c.FullPath(), c.GetHeader("Authorization") != ""))
+		}
+		sum := sha256.Sum256(body)
+		etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+		header.Set("ETag", etag)
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			original.WriteHeader(http.StatusNotModified)
+			return
+		}
+		original.Write(body)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison RFC 9110 prescribes for it.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
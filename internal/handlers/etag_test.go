@@ -0,0 +1,26 @@
+package handlers
+
+import "testing"
+
+func TestCacheControl(t *testing.T) {
+	ages := loadMaxAges("/admin/config=600,/search/:language=30")
+	tests := []struct {
+		route      string
+		authorized bool
+		want       string
+	}{
+		{"/search/:language", false, "public, max-age=30"},
+		{"/search/:language", true, "private, max-age=30"},
+		{"/movie/:language/:id", false, "public, max-age=60"},
+		{"/downloads", false, "public, no-cache"},
+		{"/downloads/:id", true, "private, no-cache"},
+		{"/admin/config", true, "private, no-store"},
+		{"/admin/cache", false, "private, no-store"},
+		{"/config", true, "private, no-store"},
+	}
+	for _, tt := range tests {
+		if got := ages.cacheControl(tt.route, tt.authorized); got != tt.want {
+			t.Errorf("cacheControl(%q, authorized=%v) = %q, want %q", tt.route, tt.authorized, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+const (
+	// eventsHeartbeat is how often an idle event stream gets a comment line,
+	// so proxies don't close it for inactivity.
+	eventsHeartbeat = 30 * time.Second
+	// eventsBuffer is how many events a slow subscriber may fall behind by
+	// before further ones are dropped for it.
+	eventsBuffer = 16
+)
+
+// ReleaseEvent announces a movie that has newly appeared in a language's
+// recent listing.
+type ReleaseEvent struct {
+	Language   string            `json:"language"`
+	Movie      models.MovieEntry `json:"movie"`
+	DetectedAt time.Time         `json:"detected_at"`
+}
+
+// releaseHub fans new-release events out to /events subscribers. The prewarm
+// refresher hands it every recent listing it scrapes; the first one for a
+// language is the baseline, and after that any movie it hasn't seen before
+// is announced. Movies that drop off the listing stay known, so one that
+// moves between pages isn't announced twice.
+type releaseHub struct {
+	mu     sync.Mutex
+	known  map[string]map[string]struct{}            // language -> movie key
+	subs   map[string]map[chan ReleaseEvent]struct{} // language -> subscribers
+	closed bool
+}
+
+var releases = &releaseHub{known: make(map[string]map[string]struct{}), subs: make(map[string]map[chan ReleaseEvent]struct{})}
+
+// watchedLanguages are the languages the refresher scrapes, and so the only
+// ones with events.
+var watchedLanguages []string
+
+// observe compares a freshly scraped recent listing with the movies already
+// known for language and publishes an event for each new one.
+func (h *releaseHub) observe(language string, movies []models.MovieEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	known, baseline := h.known[language], h.known[language] == nil
+	if baseline {
+		known = make(map[string]struct{}, len(movies))
+		h.known[language] = known
+	}
+	now := time.Now().UTC()
+	for _, m := range movies {
+		if m.Key() == "" {
+			continue
+		}
+		if _, ok := known[m.Key()]; ok {
+			continue
+		}
+		known[m.Key()] = struct{}{}
+		if baseline {
+			continue
+		}
+		event := ReleaseEvent{Language: language, Movie: m, DetectedAt: now}
+		for ch := range h.subs[language] {
+			select {
+			case ch <- event:
+			default:
+				slog.Debug("events: subscriber too slow, dropping event", "language", language, "movie", m.ID)
+			}
+		}
+	}
+}
+
+// subscribe returns a channel of language's events and a func to stop them.
+// The channel is closed when the server shuts down.
+func (h *releaseHub) subscribe(language string) (<-chan ReleaseEvent, func()) {
+	ch := make(chan ReleaseEvent, eventsBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if h.subs[language] == nil {
+		h.subs[language] = make(map[chan ReleaseEvent]struct{})
+	}
+	h.subs[language][ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[language][ch]; ok {
+			delete(h.subs[language], ch)
+			close(ch)
+		}
+	}
+}
+
+// close ends every event stream. Streams never finish on their own, so
+// without this a shutdown would wait out its whole grace period.
+func (h *releaseHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range h.subs {
+		for ch := range subs {
+			close(ch)
+		}
+	}
+	h.subs = nil
+}
+
+// releaseEvents answers /events/:language with a Server-Sent Events stream
+// that sends a "release" event, a JSON ReleaseEvent, for each movie the
+// refresher finds new in the recent listing. Only languages in
+// PREWARM_LANGUAGES are refreshed in the background, so others get a 404.
+func releaseEvents(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	if !slices.Contains(watchedLanguages, language) {
+		respondError(c, http.StatusNotFound, "language_not_watched", "no new-release events for this language; add it to PREWARM_LANGUAGES")
+		return
+	}
+	events, unsubscribe := releases.subscribe(language)
+	defer unsubscribe()
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // keep nginx from buffering the stream
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false // shutting down
+			}
+			c.SSEvent("release", event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case <-ctx.Done():
+			return false
+		}
+		return true
+	})
+}
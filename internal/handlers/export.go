@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+// maxExportPages bounds how many recent pages per language one export walks.
+const maxExportPages = 10
+
+type ExportBlock struct {
+	Language string              `json:"language"`
+	Movies   []models.MovieEntry `json:"movies"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// exportCatalog streams the recent listing of each requested language (all
+// supported languages unless languages= narrows it) as NDJSON, one language
+// block per line, gzipped when the client accepts it. Each block is flushed as soon as it is written, so memory stays flat no
+// matter how large the export grows. Because the status is already sent, a
+// language that fails to scrape gets an error field instead of aborting.
+func exportCatalog(c *gin.Context) {
+	pages, err := strconv.Atoi(c.DefaultQuery("pages", "1"))
+	if err != nil || pages < 1 {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "pages must be a positive integer")
+		return
+	}
+	pages = min(pages, maxExportPages)
+	languages, ok := languageList(c, "languages")
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Vary", "Accept-Encoding")
+	var out io.Writer = c.Writer
+	flush := func() {}
+	if acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		c.Header("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(c.Writer)
+		defer gz.Close()
+		out = gz
+		flush = func() { gz.Flush() }
+	}
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(out)
+	ctx := c.Request.Context()
+	for _, language := range languages {
+		block := ExportBlock{Language: language, Movies: []models.MovieEntry{}}
+		base, _ := browseUrl(language, "recent")
+		for page := 1; page <= pages; page++ {
+			result, err := cachedScrape(ctx, language, pageUrl(base, page))
+			if err != nil {
+				block.Error = err.Error()
+				break
+			}
+			block.Movies = append(block.Movies, result.Movies...)
+			if !result.HasNext {
+				break
+			}
+		}
+		if err := encoder.Encode(block); err != nil {
+			return // client went away
+		}
+		flush()
+		c.Writer.Flush()
+	}
+}
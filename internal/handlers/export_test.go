@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportStreamsGzippedNDJSON(t *testing.T) {
+	fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		page := "results.html"
+		if r.URL.Query().Get("page") == "2" {
+			page = "results_last.html"
+		}
+		http.ServeFile(w, r, filepath.Join(fixtureDir, page))
+	})
+
+	tests := []struct {
+		accept       string
+		wantEncoding string
+	}{
+		{"gzip, br", "gzip"},
+		{"br", ""}, // export only gzips
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run("Accept-Encoding "+tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/export?languages=tamil,hindi&pages=3", nil)
+			req.Header.Set("Accept-Encoding", tt.accept)
+			w := httptest.NewRecorder()
+			NewRouter().ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d; body %s", w.Code, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			var body io.Reader = w.Body
+			if tt.wantEncoding == "gzip" {
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = gz
+			}
+
+			var blocks []ExportBlock
+			lines := bufio.NewScanner(body)
+			lines.Buffer(nil, 1<<20)
+			for lines.Scan() {
+				var block ExportBlock
+				if err := json.Unmarshal(lines.Bytes(), &block); err != nil {
+					t.Fatalf("line %d isn't a JSON block: %v", len(blocks)+1, err)
+				}
+				blocks = append(blocks, block)
+			}
+			if err := lines.Err(); err != nil {
+				t.Fatalf("reading the stream: %v", err)
+			}
+			if len(blocks) != 2 || blocks[0].Language != "tamil" || blocks[1].Language != "hindi" {
+				t.Fatalf("got %d blocks: %+v", len(blocks), blocks)
+			}
+			for _, block := range blocks {
+				// Page 2 is the last, so the walk stops there: 20 + 7 movies.
+				if block.Error != "" || len(block.Movies) != 27 {
+					t.Errorf("%s block: %d movies, error %q; want 27 and none", block.Language, len(block.Movies), block.Error)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+// Einthusan doesn't publish release dates on its listings, so a feed item's
+// date is when this server first saw the movie in the recent listing.
+// Movies that drop off the listing are forgotten.
+var (
+	firstSeenMu sync.Mutex
+	firstSeen   = make(map[string]map[string]time.Time) // language -> movie key -> first seen
+)
+
+// stampFirstSeen returns the first-seen time of each movie, recording now
+// for any it hasn't seen before.
+func stampFirstSeen(language string, movies []models.MovieEntry) []time.Time {
+	firstSeenMu.Lock()
+	defer firstSeenMu.Unlock()
+	now := time.Now().UTC()
+	previous := firstSeen[language]
+	current := make(map[string]time.Time, len(movies))
+	stamps := make([]time.Time, len(movies))
+	for i, m := range movies {
+		seen, ok := previous[m.Key()]
+		if !ok {
+			seen = now
+		}
+		current[m.Key()] = seen
+		stamps[i] = seen
+	}
+	firstSeen[language] = current
+	return stamps
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title     string        `xml:"title"`
+	Link      string        `xml:"link"`
+	GUID      rssGUID       `xml:"guid"`
+	PubDate   string        `xml:"pubDate"`
+	Enclosure *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Links   []atomLink `xml:"link"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// isoLanguages maps supported languages to the codes RSS's <language> takes.
+var isoLanguages = map[string]string{
+	"tamil": "ta", "hindi": "hi", "telugu": "te", "malayalam": "ml",
+	"kannada": "kn", "bengali": "bn", "marathi": "mr", "punjabi": "pa",
+}
+
+// releaseFeed answers /feed/:file, where file is a language followed by
+// .rss or .atom, with the language's recent listing as a feed.
+func releaseFeed(c *gin.Context) {
+	file := c.Param("file")
+	format := strings.TrimPrefix(path.Ext(file), ".")
+	if format != "rss" && format != "atom" {
+		respondError(c, http.StatusNotFound, "unsupported_feed_format", "feed must end in .rss or .atom")
+		return
+	}
+	language, ok := checkLanguage(c, strings.TrimSuffix(file, path.Ext(file)))
+	if !ok {
+		return
+	}
+	listingUrl := browseUrlFor(language, "recent")
+	result, err := cachedScrape(c.Request.Context(), language, listingUrl)
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	stamps := stampFirstSeen(language, result.Movies)
+	updated := time.Now().UTC()
+	if len(stamps) > 0 {
+		updated = slices.MaxFunc(stamps, time.Time.Compare)
+	}
+
+	title := fmt.Sprintf("New %s movies on Einthusan", strings.ToUpper(language[:1])+language[1:])
+	self := requestUrl(c)
+	if format == "atom" {
+		feed := atomFeed{
+			ID:      self,
+			Title:   title,
+			Updated: updated.Format(time.RFC3339),
+			Links:   []atomLink{{Href: self, Rel: "self", Type: "application/atom+xml"}, {Href: listingUrl, Rel: "alternate", Type: "text/html"}},
+			Author:  atomAuthor{Name: "Einthusan"},
+			Entries: []atomEntry{},
+		}
+		for i, m := range result.Movies {
+			entry := atomEntry{ID: m.PageUrl, Title: feedTitle(m), Updated: stamps[i].Format(time.RFC3339), Links: []atomLink{{Href: m.PageUrl, Rel: "alternate", Type: "text/html"}}}
+			if m.ImgUrl != "" {
+				entry.Links = append(entry.Links, atomLink{Href: m.ImgUrl, Rel: "enclosure", Type: "image/jpeg"})
+			}
+			feed.Entries = append(feed.Entries, entry)
+		}
+		writeXML(c, "application/atom+xml; charset=utf-8", feed)
+		return
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         title,
+			Link:          listingUrl,
+			Description:   fmt.Sprintf("Movies recently added to Einthusan's %s catalog.", language),
+			Language:      isoLanguages[language],
+			LastBuildDate: updated.Format(time.RFC1123Z),
+			Self:          atomLink{Href: self, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	for i, m := range result.Movies {
+		item := rssItem{Title: feedTitle(m), Link: m.PageUrl, GUID: rssGUID{IsPermaLink: true, Value: m.PageUrl}, PubDate: stamps[i].Format(time.RFC1123Z)}
+		if m.ImgUrl != "" {
+			item.Enclosure = &rssEnclosure{URL: m.ImgUrl, Type: "image/jpeg"}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	writeXML(c, "application/rss+xml; charset=utf-8", feed)
+}
+
+func feedTitle(m models.MovieEntry) string {
+	if m.Year > 0 {
+		return fmt.Sprintf("%s (%d)", m.Title, m.Year)
+	}
+	return m.Title
+}
+
+// requestUrl rebuilds the URL the client used, for a feed's self link.
+func requestUrl(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.RequestURI()
+}
+
+// writeXML sends doc as an indented XML document.
+func writeXML(c *gin.Context, contentType string, doc any) {
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		respondInternalError(c, "internal_error", err)
+		return
+	}
+	if cacheStatus := scopeFrom(c.Request.Context()).cacheStatus(); cacheStatus != "" {
+		c.Header("X-Cache", cacheStatus)
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), data...))
+}
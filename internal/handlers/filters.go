@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+// Filter controls change only when Einthusan redesigns the finder, so cache them for a day.
+const filtersTTL = 24 * time.Hour
+
+type FilterOption struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// FiltersResponse lists the finder controls available for a language. A
+// control that the upstream page doesn't offer is omitted entirely.
+type FiltersResponse struct {
+	Language string         `json:"language"`
+	Sort     []FilterOption `json:"sort,omitempty"`
+	Genres   []FilterOption `json:"genres,omitempty"`
+	Years    []FilterOption `json:"years,omitempty"`
+	Decades  []FilterOption `json:"decades,omitempty"`
+	Quality  []FilterOption `json:"quality,omitempty"`
+}
+
+var filtersCache = newTTLCache[*FiltersResponse](filtersTTL)
+
+// sortFinds are the find= values that order the whole catalog rather than narrow it.
+var sortFinds = map[string]bool{"recent": true, "popularity": true, "rating": true, "alphabetical": true}
+
+func scrapeFilters(ctx context.Context, language string) (*FiltersResponse, error) {
+	if filters, ok := filtersCache.get(language); ok {
+		return filters, nil
+	}
+	res, err := fetchUpstream(ctx, fmt.Sprintf("%s/movie/browse/?lang=%s", scraper.Einthusan.BaseURL(), url.QueryEscape(language)))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := &FiltersResponse{Language: language}
+	seen := make(map[string]bool)
+	add := func(list *[]FilterOption, kind, label, value string) {
+		label = strings.TrimSpace(label)
+		if value == "" || seen[kind+"="+value] {
+			return
+		}
+		if label == "" {
+			label = value
+		}
+		seen[kind+"="+value] = true
+		*list = append(*list, FilterOption{Label: label, Value: value})
+	}
+
+	// Every finder control is a link into /movie/results/; its query string says what it filters.
+	doc.Find(`a[href*="/movie/results/"]`).Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		q := u.Query()
+		label := s.Text()
+		switch find := strings.ToLower(q.Get("find")); {
+		case q.Get("quality") != "":
+			add(&filters.Quality, "quality", label, q.Get("quality"))
+		case find == "year":
+			add(&filters.Years, "year", label, q.Get("year"))
+		case find == "decade":
+			add(&filters.Decades, "decade", label, q.Get("decade"))
+		case find == "genre":
+			add(&filters.Genres, "genre", label, q.Get("genre"))
+		case sortFinds[find]:
+			add(&filters.Sort, "sort", label, q.Get("find"))
+		}
+	})
+
+	filtersCache.set(language, filters)
+	return filters, nil
+}
+
+func listFilters(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	filters, err := scrapeFilters(c.Request.Context(), language)
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	respond(c, http.StatusOK, filters)
+}
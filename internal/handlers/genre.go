@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// knownGenres are the genre slugs Einthusan's finder accepts.
+var knownGenres = []string{"action", "comedy", "crime", "drama", "family", "horror", "romance", "thriller"}
+
+// GenreResponse is BrowseResponse for a single named genre.
+type GenreResponse struct {
+	Genre          string              `json:"genre"`
+	HasMore        bool                `json:"has_more"`
+	Language       string              `json:"language"`
+	Movies         []models.MovieEntry `json:"movies"`
+	NextPage       int                 `json:"next_page"`
+	Page           int                 `json:"page"`
+	PageSize       int                 `json:"page_size"`                 // Movies on a full upstream page; see count for this response
+	Count          int                 `json:"count"`                     // len(movies), for pagination UIs
+	Reason         string              `json:"reason,omitempty"`          // Why Movies is empty
+	TotalPages     int                 `json:"total_pages,omitempty"`     // 0 when the upstream doesn't say
+	TotalResults   int                 `json:"total_results,omitempty"`   // 0 when the upstream doesn't say
+	ScrapeDegraded bool                `json:"scrape_degraded,omitempty"` // See SearchResponse
+}
+
+// browseGenre lists a language's movies in one genre, paginated like the
+// other browse endpoints.
+func browseGenre(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	genre := strings.ToLower(strings.TrimSpace(c.Param("genre")))
+	if !slices.Contains(knownGenres, genre) {
+		body := errorBody("unsupported_genre", "unsupported genre")
+		body["supported"] = knownGenres
+		abortWithError(c, http.StatusBadRequest, body)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, ok := parsePageSize(c)
+	if !ok {
+		return
+	}
+	full, ok := wantsFullFields(c)
+	if !ok {
+		return
+	}
+
+	pages, err := fetchPages(c.Request.Context(), urlPages(language, genreUrl(language, genre)), page, pageSize)
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	if !full {
+		basicFields(pages.Movies)
+	}
+	respond(c, http.StatusOK, GenreResponse{Genre: genre, HasMore: pages.HasMore, Language: language, Movies: pages.Movies, NextPage: pages.nextPage(), Page: page, PageSize: scraper.PageSize(), Count: len(pages.Movies), Reason: listingReason(pages.Movies, pages.Degraded, reasonUpstreamEmpty), TotalPages: pages.TotalPages, TotalResults: pages.TotalResults, ScrapeDegraded: pages.Degraded})
+}
+
+// genreUrl is the first page of a genre's Einthusan finder listing.
+func genreUrl(language, genre string) string {
+	return fmt.Sprintf("%s/movie/results/?find=Genre&genre=%s&lang=%s", scraper.Einthusan.BaseURL(), url.QueryEscape(genre), language)
+}
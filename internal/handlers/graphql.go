@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// The GraphQL schema mirrors the JSON API: types and fields carry the same
+// names as the REST responses, and resolvers go through the same providers
+// and cache, so a query costs no more upstream requests than the equivalent
+// REST calls. A movie's details field fetches its watch page, which lets a
+// client search and read details in one round trip.
+
+// graphqlMovie is a listed movie along with the provider and language it
+// was listed under, which its details field needs.
+type graphqlMovie struct {
+	source   provider
+	language string
+	entry    models.MovieEntry
+}
+
+// graphqlPage is a listing response along with the provider that served it,
+// so its movies can read their details from the same one.
+type graphqlPage struct {
+	source provider
+	page   any
+}
+
+// pageField resolves a page type's field from the wrapped response by its json name.
+func pageField(p graphql.ResolveParams) (any, error) {
+	p.Source = p.Source.(graphqlPage).page
+	return graphql.DefaultResolveFn(p)
+}
+
+// entryField resolves a Movie field from the wrapped MovieEntry by its json name.
+func entryField(p graphql.ResolveParams) (any, error) {
+	p.Source = p.Source.(graphqlMovie).entry
+	return graphql.DefaultResolveFn(p)
+}
+
+func movieDetails(p graphql.ResolveParams) (any, error) {
+	m := p.Source.(graphqlMovie)
+	if m.entry.ID == "" {
+		return nil, nil
+	}
+	detail, err := m.source.details(p.Context, m.language, m.entry.ID)
+	if errors.Is(err, scraper.ErrNotFound) {
+		return nil, nil
+	}
+	return detail, err
+}
+
+// listedMovies resolves a page type's movies field.
+func listedMovies(p graphql.ResolveParams) (any, error) {
+	wrapper := p.Source.(graphqlPage)
+	var language string
+	var movies []models.MovieEntry
+	switch page := wrapper.page.(type) {
+	case models.SearchResponse:
+		language, movies = page.Language, page.Movies
+	case models.BrowseResponse:
+		language, movies = page.Language, page.Movies
+	case models.ActorResponse:
+		language, movies = page.Language, page.Movies
+	}
+	wrapped := make([]graphqlMovie, len(movies))
+	for i, m := range movies {
+		wrapped[i] = graphqlMovie{source: wrapper.source, language: language, entry: m}
+	}
+	return wrapped, nil
+}
+
+var graphqlSchema = mustGraphQLSchema()
+
+func mustGraphQLSchema() graphql.Schema {
+	nonNull := graphql.NewNonNull
+	list := func(t graphql.Type) graphql.Type { return nonNull(graphql.NewList(nonNull(t))) }
+
+	castMember := graphql.NewObject(graphql.ObjectConfig{Name: "CastMember", Fields: graphql.Fields{
+		"id":   {Type: graphql.String},
+		"name": {Type: nonNull(graphql.String)},
+		"role": {Type: graphql.String},
+	}})
+	subtitle := graphql.NewObject(graphql.ObjectConfig{Name: "SubtitleTrack", Fields: graphql.Fields{
+		"language": {Type: nonNull(graphql.String)},
+		"url":      {Type: nonNull(graphql.String)},
+	}})
+	detail := graphql.NewObject(graphql.ObjectConfig{Name: "MovieDetail", Fields: graphql.Fields{
+		"id":          {Type: nonNull(graphql.String)},
+		"language":    {Type: nonNull(graphql.String)},
+		"title":       {Type: nonNull(graphql.String)},
+		"img_url":     {Type: graphql.String},
+		"synopsis":    {Type: graphql.String},
+		"year":        {Type: graphql.Int},
+		"duration":    {Type: graphql.String},
+		"director":    {Type: graphql.String},
+		"rating":      {Type: graphql.Float},
+		"genres":      {Type: list(graphql.String)},
+		"trailer_url": {Type: graphql.String},
+		"poster_hd":   {Type: graphql.String},
+		"cast":        {Type: list(castMember)},
+		"subtitles":   {Type: list(subtitle)},
+		"stream_url":  {Type: graphql.String},
+		"warnings":    {Type: graphql.NewList(nonNull(graphql.String))},
+	}})
+	movie := graphql.NewObject(graphql.ObjectConfig{Name: "Movie", Fields: graphql.Fields{
+		"id":          {Type: nonNull(graphql.String), Resolve: entryField},
+		"title":       {Type: nonNull(graphql.String), Resolve: entryField},
+		"year":        {Type: nonNull(graphql.Int), Description: "0 when the listing doesn't show one", Resolve: entryField},
+		"img_url":     {Type: nonNull(graphql.String), Resolve: entryField},
+		"page_url":    {Type: nonNull(graphql.String), Resolve: entryField},
+		"duration":    {Type: graphql.String, Resolve: entryField},
+		"synopsis":    {Type: graphql.String, Resolve: entryField},
+		"views":       {Type: graphql.Int, Resolve: entryField},
+		"trailer_url": {Type: graphql.String, Description: "Only filled in by search(enrich: true)", Resolve: entryField},
+		"poster_hd":   {Type: graphql.String, Description: "Only filled in by search(enrich: true)", Resolve: entryField},
+		"details":     {Type: detail, Description: "The movie's own page; null when it has none", Resolve: movieDetails},
+	}})
+
+	// Fields every paged listing shares.
+	pageFields := func(extra graphql.Fields) graphql.Fields {
+		fields := graphql.Fields{
+			"language":        {Type: nonNull(graphql.String)},
+			"page":            {Type: nonNull(graphql.Int)},
+			"next_page":       {Type: nonNull(graphql.Int)},
+			"has_more":        {Type: nonNull(graphql.Boolean)},
+			"reason":          {Type: graphql.String},
+			"scrape_degraded": {Type: nonNull(graphql.Boolean)},
+			"movies":          {Type: list(movie), Resolve: listedMovies},
+		}
+		for name, field := range extra {
+			fields[name] = field
+		}
+		for _, field := range fields {
+			if field.Resolve == nil {
+				field.Resolve = pageField
+			}
+		}
+		return fields
+	}
+	searchPage := graphql.NewObject(graphql.ObjectConfig{Name: "SearchResults", Fields: pageFields(graphql.Fields{
+		"q":               {Type: nonNull(graphql.String)},
+		"estimated_total": {Type: graphql.Int},
+	})})
+	browsePage := graphql.NewObject(graphql.ObjectConfig{Name: "BrowseResults", Fields: pageFields(graphql.Fields{
+		"category":      {Type: nonNull(graphql.String)},
+		"total_pages":   {Type: graphql.Int},
+		"total_results": {Type: graphql.Int},
+	})})
+	actorPage := graphql.NewObject(graphql.ObjectConfig{Name: "ActorResults", Fields: pageFields(graphql.Fields{
+		"actor_id":      {Type: nonNull(graphql.String)},
+		"actor_name":    {Type: nonNull(graphql.String)},
+		"total_pages":   {Type: graphql.Int},
+		"total_results": {Type: graphql.Int},
+	})})
+
+	page := &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1}
+	source := &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: defaultProvider}
+	query := graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"search": {
+			Type: nonNull(searchPage),
+			Args: graphql.FieldConfigArgument{
+				"provider":  source,
+				"language":  {Type: nonNull(graphql.String)},
+				"q":         {Type: nonNull(graphql.String)},
+				"page":      page,
+				"min_score": {Type: graphql.Int},
+				"limit":     {Type: graphql.Int},
+				"sort":      {Type: graphql.String, DefaultValue: "relevance"},
+				"enrich":    {Type: graphql.Boolean, DefaultValue: false},
+				"strict":    {Type: graphql.Boolean, DefaultValue: false},
+			},
+			Resolve: resolveSearch,
+		},
+		"browse": {
+			Type: nonNull(browsePage),
+			Args: graphql.FieldConfigArgument{
+				"provider":  source,
+				"language":  {Type: nonNull(graphql.String)},
+				"category":  {Type: graphql.String, DefaultValue: "recent"},
+				"page":      page,
+				"page_size": {Type: graphql.Int},
+			},
+			Resolve: resolveBrowse,
+		},
+		"actor": {
+			Type: actorPage,
+			Args: graphql.FieldConfigArgument{
+				"provider":  source,
+				"language":  {Type: nonNull(graphql.String)},
+				"id":        {Type: nonNull(graphql.String)},
+				"page":      page,
+				"page_size": {Type: graphql.Int},
+			},
+			Resolve: resolveActor,
+		},
+		"movie": {
+			Type: detail,
+			Args: graphql.FieldConfigArgument{
+				"provider": source,
+				"language": {Type: nonNull(graphql.String)},
+				"id":       {Type: nonNull(graphql.String)},
+			},
+			Resolve: resolveMovie,
+		},
+	}})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		panic(fmt.Sprintf("graphql schema: %v", err))
+	}
+	return schema
+}
+
+// graphqlSource checks the provider and language arguments, since
+// resolvers can't use requireProvider's 400 response.
+func graphqlSource(p graphql.ResolveParams) (provider, string, error) {
+	name, _ := p.Args["provider"].(string)
+	source, ok := providers[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported provider %q, must be one of %s", name, strings.Join(providerNames(), ", "))
+	}
+	raw, _ := p.Args["language"].(string)
+	language := strings.ToLower(strings.TrimSpace(raw))
+	if served := source.info().Languages; !slices.Contains(served, language) {
+		return nil, "", fmt.Errorf("unsupported language %q, must be one of %s", raw, strings.Join(served, ", "))
+	}
+	return source, language, nil
+}
+
+// graphqlPageSize reads the optional page_size argument with parsePageSize's bounds.
+func graphqlPageSize(p graphql.ResolveParams) (int, error) {
+	size, _ := p.Args["page_size"].(int)
+	if size < 0 || size > maxPageSize {
+		return 0, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+	}
+	return size, nil
+}
+
+func resolveSearch(p graphql.ResolveParams) (any, error) {
+	source, language, err := graphqlSource(p)
+	if err != nil {
+		return nil, err
+	}
+	query := strings.Join(strings.Fields(p.Args["q"].(string)), " ")
+	if query == "" {
+		return nil, errors.New("q is required")
+	}
+	opts := searchOptions{order: p.Args["sort"].(string), enrich: p.Args["enrich"].(bool), strict: p.Args["strict"].(bool)}
+	if opts.order != "relevance" && opts.order != "title" && opts.order != "year" {
+		return nil, errors.New("sort must be relevance, title or year")
+	}
+	opts.minScore, opts.filter = p.Args["min_score"].(int)
+	if opts.filter && opts.minScore < 0 {
+		return nil, errors.New("min_score must be a non-negative integer")
+	}
+	if limit, ok := p.Args["limit"].(int); ok {
+		if limit < 1 {
+			return nil, errors.New("limit must be a positive integer")
+		}
+		opts.limit = limit
+	}
+	resp, err := rankedSearch(p.Context, source, language, query, p.Args["page"].(int), opts)
+	if err != nil {
+		return nil, err
+	}
+	return graphqlPage{source: source, page: resp}, nil
+}
+
+func resolveBrowse(p graphql.ResolveParams) (any, error) {
+	source, language, err := graphqlSource(p)
+	if err != nil {
+		return nil, err
+	}
+	category := strings.ToLower(p.Args["category"].(string))
+	if categories := source.info().Categories; !slices.Contains(categories, category) {
+		return nil, errors.New("category must be one of " + strings.Join(categories, ", "))
+	}
+	pageSize, err := graphqlPageSize(p)
+	if err != nil {
+		return nil, err
+	}
+	page := p.Args["page"].(int)
+	pages, err := fetchPages(p.Context, func(ctx context.Context, page int) (scraper.Listing, error) {
+		return source.browse(ctx, language, category, "", page)
+	}, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return graphqlPage{source: source, page: browseResponse(category, language, page, pages)}, nil
+}
+
+// resolveActor returns null for an unknown actor code, as /actors 404s.
+func resolveActor(p graphql.ResolveParams) (any, error) {
+	source, language, err := graphqlSource(p)
+	if err != nil {
+		return nil, err
+	}
+	actorCode := p.Args["id"].(string)
+	if isKnownMissing("actor", language+"/"+actorCode) {
+		return nil, nil
+	}
+	pageSize, err := graphqlPageSize(p)
+	if err != nil {
+		return nil, err
+	}
+	page := p.Args["page"].(int)
+	pages, err := fetchPages(p.Context, func(ctx context.Context, page int) (scraper.Listing, error) {
+		return source.byActor(ctx, language, actorCode, page)
+	}, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages.Movies) == 0 && pages.Heading == "" && !pages.Degraded {
+		markMissing("actor", language+"/"+actorCode)
+		return nil, nil
+	}
+	return graphqlPage{source: source, page: models.ActorResponse{
+		ActorID: actorCode, ActorName: resolveActorName(p.Context, language, actorCode, pages.Heading),
+		HasMore: pages.HasMore, Language: language, Movies: pages.Movies, NextPage: pages.nextPage(), Page: page,
+		PageSize: scraper.PageSize(), Count: len(pages.Movies), Reason: listingReason(pages.Movies, pages.Degraded, reasonUpstreamEmpty),
+		TotalPages: pages.TotalPages, TotalResults: pages.TotalResults, ScrapeDegraded: pages.Degraded,
+	}}, nil
+}
+
+// resolveMovie returns null for an unknown movie, as /movie 404s.
+func resolveMovie(p graphql.ResolveParams) (any, error) {
+	source, language, err := graphqlSource(p)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := source.details(p.Context, language, p.Args["id"].(string))
+	if errors.Is(err, scraper.ErrNotFound) {
+		return nil, nil
+	}
+	return detail, err
+}
+
+// graphqlRequest is the standard GraphQL-over-HTTP request body.
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// serveGraphQL answers POST /graphql. Query errors, including failed
+// scrapes, come back in the result's errors list with a 200, as GraphQL
+// clients expect; only a body that isn't a GraphQL request is a 400.
+func serveGraphQL(c *gin.Context) {
+	var req graphqlRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Query) == "" {
+		respondError(c, http.StatusBadRequest, "invalid_body", "body must be a JSON object with a query")
+		return
+	}
+	result := graphql.Do(graphql.Params{
+		Schema:         graphqlSchema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        c.Request.Context(),
+	})
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+const (
+	// healthCheckTimeout keeps the probe itself from hanging on a dead upstream.
+	healthCheckTimeout = 3 * time.Second
+	// healthCacheTTL stops frequent platform probes from each reaching Einthusan.
+	healthCacheTTL = 10 * time.Second
+)
+
+var healthCache = newTTLCache[bool](healthCacheTTL)
+
+// startedAt is when the process started, for /healthz's uptime.
+var startedAt = time.Now()
+
+const defaultReadyCheckTTLSeconds = 30
+
+// HealthzResponse is /healthz: the process is up and serving.
+type HealthzResponse struct {
+	Status        string `json:"status"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// ReadyzResponse is /readyz. Ready means a listing was fetched from a
+// mirror and the result selectors still found movies on it.
+type ReadyzResponse struct {
+	Status               string     `json:"status"` // "ready" or "not_ready"
+	Reachable            bool       `json:"reachable"`
+	Parseable            bool       `json:"parseable"`
+	Error                string     `json:"error,omitempty"`
+	CheckedAt            time.Time  `json:"checked_at"`
+	LastSuccessfulScrape *time.Time `json:"last_successful_scrape,omitempty"`
+}
+
+// readyCheck holds the last readiness probe; it is re-run at most once per
+// READY_CHECK_TTL_SECONDS, however often the platform polls.
+var readyCheck struct {
+	mu     sync.Mutex
+	result ReadyzResponse
+	ttl    time.Duration
+}
+
+func init() {
+	readyCheck.ttl = time.Duration(EnvInt("READY_CHECK_TTL_SECONDS", defaultReadyCheckTTLSeconds)) * time.Second
+}
+
+// healthz reports liveness only; it never touches the network.
+func healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthzResponse{Status: "ok", UptimeSeconds: int64(time.Since(startedAt).Seconds())})
+}
+
+// readyz reports 200 when the upstream is reachable and still parses, and
+// 503 otherwise.
+func readyz(c *gin.Context) {
+	readyCheck.mu.Lock()
+	if time.Since(readyCheck.result.CheckedAt) >= readyCheck.ttl {
+		readyCheck.result = probeReadiness(context.WithoutCancel(c.Request.Context()))
+	}
+	resp := readyCheck.result
+	readyCheck.mu.Unlock()
+
+	if last := metrics.lastSuccessfulScrape(); !last.IsZero() {
+		resp.LastSuccessfulScrape = &last
+	}
+	status := http.StatusOK
+	if resp.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, resp)
+}
+
+// probeReadiness scrapes the first page of DEFAULT_LANGUAGE's (or the first
+// supported language's) recent listing, bypassing the cache.
+func probeReadiness(ctx context.Context) ReadyzResponse {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	resp := ReadyzResponse{Status: "not_ready", CheckedAt: time.Now()}
+	result, err := upstream.ScrapeListing(ctx, browseUrlFor(cmp.Or(defaultLanguage, supportedLanguages[0]), "recent"))
+	switch {
+	case errors.Is(err, scraper.ErrUnexpectedPage), errors.Is(err, scraper.ErrUpstreamBlocked):
+		resp.Reachable = true
+		resp.Error = err.Error()
+	case err != nil:
+		resp.Error = err.Error()
+	case len(result.Movies) == 0:
+		resp.Reachable = true
+		resp.Error = "recent listing parsed to no movies; the page markup may have changed"
+	default:
+		resp.Reachable, resp.Parseable, resp.Status = true, true, "ready"
+	}
+	return resp
+}
+
+// health reports 200 when any Einthusan mirror answers a HEAD request, and
+// 503 when none does.
+func health(c *gin.Context) {
+	reachable, ok := healthCache.get("upstream")
+	if !ok {
+		reachable = upstreamReachable(c.Request.Context())
+		healthCache.set("upstream", reachable)
+	}
+	if !reachable {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "degraded", "upstream": "unreachable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "upstream": "reachable"})
+}
+
+func upstreamReachable(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	for _, target := range scraper.Einthusan.Candidates(scraper.Einthusan.BaseURL() + "/") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+		if err != nil {
+			continue
+		}
+		setBrowserHeaders(req)
+		res, err := httpClient.Do(req)
+		if err != nil {
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode < http.StatusInternalServerError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+// TestMain lifts the rate limits, which would otherwise turn the suite's own
+// requests away, and keeps gin's route listing out of the test output.
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	scrapeLimiter = newScrapeLimiter(0, 0)
+	clientLimits = newClientLimiter(0, 0, "")
+	os.Exit(m.Run())
+}
+
+// fakeUpstream serves handler as the only Einthusan mirror for the rest of
+// the test and counts the requests it gets. Pacing and retries are switched off,
+// and the caches and breaker start empty, so every test sees the upstream
+// afresh. Any 429 backoff it causes is lifted afterwards.
+func fakeUpstream(t *testing.T, handler http.HandlerFunc) *atomic.Int64 {
+	t.Helper()
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	mirrors, pacing, circuit, attempts := scraper.Einthusan, scheduler, breaker, upstreamAttempts
+	scraper.Einthusan = scraper.NewMirrorSet(srv.URL)
+	scheduler = newUpstreamScheduler(0, 0, 0, 0)
+	breaker = newCircuitBreaker()
+	upstreamAttempts = 1
+	t.Cleanup(func() { scraper.Einthusan, scheduler, breaker, upstreamAttempts = mirrors, pacing, circuit, attempts })
+
+	cache.flush("", "")
+	missingIDs.clear()
+	availabilityCache.clear()
+	t.Cleanup(func() {
+		cache.flush("", "")
+		missingIDs.clear()
+		availabilityCache.clear()
+		upstreamBackoff.mu.Lock()
+		upstreamBackoff.until = time.Time{}
+		upstreamBackoff.mu.Unlock()
+	})
+	return &hits
+}
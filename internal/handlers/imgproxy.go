@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// imageFetchTimeout is deliberately shorter than a listing scrape: a poster
+// that takes longer than this isn't worth waiting for. Both limits are
+// variables so tests can tighten them.
+var (
+	imageFetchTimeout       = 5 * time.Second
+	maxImageBytes     int64 = 5 << 20
+)
+
+const (
+	// minImageWidth and maxImageWidth bound the w= resize parameter.
+	minImageWidth = 16
+	maxImageWidth = 1280
+	// imageCacheControl lets browsers and CDNs keep proxied posters for a
+	// month; a poster URL's content doesn't change.
+	imageCacheControl = "public, max-age=2592000, immutable"
+)
+
+// imageHostSuffixes limits the proxy to Einthusan's own hosts and CDN.
+var imageHostSuffixes = []string{"einthusan.tv", "einthusan.com", "einthusan.ca", "einthusan.io"}
+
+// proxyImage streams an upstream poster through the API, refusing anything
+// that is too large, too slow, or not an image. With w= the image is scaled
+// down to that width and re-encoded as JPEG; images already narrower than
+// w, or in a format that can't be decoded, are passed through unchanged.
+func proxyImage(c *gin.Context) {
+	target, err := url.Parse(c.Query("url"))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || !allowedImageHost(target.Hostname()) {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "url must be an Einthusan image URL")
+		return
+	}
+	width := 0
+	if raw := c.Query("w"); raw != "" {
+		width, err = strconv.Atoi(raw)
+		if err != nil || width < minImageWidth || width > maxImageWidth {
+			respondError(c, http.StatusBadRequest, "invalid_parameter", "w must be an integer between "+strconv.Itoa(minImageWidth)+" and "+strconv.Itoa(maxImageWidth))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), imageFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+	setBrowserHeaders(req)
+	res, err := httpClient.Do(req)
+	if err != nil {
+		imageFetchError(c, err)
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body := errorBody("upstream_error", "upstream returned "+res.Status)
+		body["upstream_status"] = res.StatusCode
+		abortWithError(c, http.StatusBadGateway, body)
+		return
+	}
+	contentType := res.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		respondError(c, http.StatusBadGateway, "upstream_error", "upstream did not return an image")
+		return
+	}
+	if res.ContentLength > maxImageBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, "image_too_large", "image too large")
+		return
+	}
+
+	// Content-Length can be absent or wrong, so enforce the cap while reading too.
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxImageBytes+1))
+	if err != nil {
+		imageFetchError(c, err)
+		return
+	}
+	if int64(len(data)) > maxImageBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, "image_too_large", "image too large")
+		return
+	}
+	if width > 0 {
+		if resized, ok := resizeImage(data, width); ok {
+			data, contentType = resized, "image/jpeg"
+		}
+	}
+	c.Header("Cache-Control", imageCacheControl)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// resizeImage scales data down to width, keeping the aspect ratio. It
+// reports false when the image can't be decoded or is no wider than width,
+// in which case the original should be served.
+func resizeImage(data []byte, width int) ([]byte, bool) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return nil, false
+	}
+	height := max(bounds.Dy()*width/bounds.Dx(), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, false
+	}
+	return out.Bytes(), true
+}
+
+func imageFetchError(c *gin.Context, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		respondError(c, http.StatusGatewayTimeout, "upstream_timeout", "image fetch timed out")
+		return
+	}
+	slog.Warn("image fetch failed", "request_id", requestID(c.Request.Context()), "error", err)
+	respondError(c, http.StatusBadGateway, "upstream_error", "image fetch failed")
+}
+
+func allowedImageHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, suffix := range imageHostSuffixes {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestProxyImageGuards(t *testing.T) {
+	hosts, timeout, limit := imageHostSuffixes, imageFetchTimeout, maxImageBytes
+	imageHostSuffixes, imageFetchTimeout, maxImageBytes = []string{"127.0.0.1"}, 50*time.Millisecond, 1024
+	t.Cleanup(func() { imageHostSuffixes, imageFetchTimeout, maxImageBytes = hosts, timeout, limit })
+
+	oversized := bytes.Repeat([]byte{0xff}, int(maxImageBytes)+1)
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantCode int
+	}{
+		{"small image", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("poster"))
+		}, http.StatusOK},
+		{"declared too large", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Content-Length", strconv.Itoa(len(oversized)))
+			w.Write(oversized)
+		}, http.StatusRequestEntityTooLarge},
+		{"too large without a length", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/jpeg")
+			for chunk := range slices.Chunk(oversized, 256) {
+				w.Write(chunk)
+				w.(http.Flusher).Flush()
+			}
+		}, http.StatusRequestEntityTooLarge},
+		{"too slow", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}, http.StatusGatewayTimeout},
+		{"not an image", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		}, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			w := httptest.NewRecorder()
+			NewRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/img?url="+url.QueryEscape(srv.URL+"/poster.jpg"), nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d; body %s", w.Code, tt.wantCode, w.Body)
+			}
+		})
+	}
+}
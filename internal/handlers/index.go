@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IndexResponse is the root listing. It is a struct rather than gin.H so its
+// field order is fixed; the endpoints map is key-sorted by every encoder we use.
+type IndexResponse struct {
+	Message      string            `json:"message"`
+	Endpoints    map[string]string `json:"endpoints"`
+	ExampleUsage string            `json:"example_usage"`
+}
+
+func index(c *gin.Context) {
+	respond(c, http.StatusOK, IndexResponse{
+		Message: "thirai api",
+		Endpoints: map[string]string{
+			"search":     "/search/:language?q=movie_title&page=1&min_score=0&limit=10&sort=relevance&enrich=false", // Updated endpoint hint
+			"index":      "/index/search?q=movie_title&language=tamil&limit=20",
+			"multi":      "/search?q=movie_title&languages=tamil,hindi&similarity=0.9&page=1",
+			"browse":     "/language/:language?category=recent|popular&window=week|month|year|alltime&page=1&page_size=40&pages=1-3&enrich=false",
+			"trending":   "/trending/:language",
+			"actors":     "/actors/:language/:actorcode?page=1&pages=1-3",
+			"genre":      "/genre/:language?action=0-4&comedy=0-4&romance=0-4&storyline=0-4&performance=0-4&ratecount=1&page=1",
+			"genre_name": "/genre/:language/:genre?page=1",
+			"decade":     "/decade/:language/:decade?page=1",
+			"year":       "/year/:language/:year?page=1",
+			"watch":      "/watch?url=einthusan_page_url or /watch?language=tamil&id=movie_id",
+			"available":  "/available/:language/:id",
+			"filters":    "/filters/:language",
+			"stats":      "/stats",
+			"health":     "/health",
+			"healthz":    "/healthz",
+			"readyz":     "/readyz",
+			"metrics":    "/metrics",
+			"usage":      "/usage",
+			"providers":  "/providers",
+			"languages":  "/languages",
+			"graphql":    "/graphql (POST a query over search, browse, actor and movie)",
+			"openapi":    "/openapi.json",
+			"docs":       "/docs",
+			"changes":    "/changes/:language",
+			"image":      "/img?url=einthusan_image_url&w=300",
+			"export":     "/export?pages=1&languages=tamil,hindi",
+			"movie":      "/movie/:language/:id",
+			"nfo":        "/movie/:language/:id.nfo",
+			"similar":    "/similar/:language/:movieid?limit=20",
+			"batch":      "/movies/batch?language=tamil (POST a JSON array of page URLs or IDs)",
+			"subtitles":  "/subtitles/:language/:movieid?lang=en&format=vtt",
+			"stream":     "/stream/:language/:movieid",
+			"match":      "/match/:language/:movieid?provider=tmdb",
+			"feed":       "/feed/:language.rss or /feed/:language.atom",
+			"stremio":    "/manifest.json",
+			"events":     "/events/:language (Server-Sent Events of new releases)",
+			"playlist":   "/playlist/:language.m3u?category=popular&limit=20",
+			"watchlist":  "/watchlist (GET, POST; DELETE /watchlist/:id)",
+			"downloads":  "/downloads (GET, POST; GET, DELETE /downloads/:id)",
+			"v2":         "/v2/search/:language, /v2/language/:language, /v2/actors/:language/:actorcode (?cursor=next_cursor)",
+		},
+		ExampleUsage: "Try /year/tamil/2025 or /search/hindi?q=pathaan&page=2",
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponsesAreDeterministic(t *testing.T) {
+	router := NewRouter()
+	get := func(target, accept string) []byte {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d", target, w.Code)
+		}
+		return w.Body.Bytes()
+	}
+	for _, tt := range []struct{ target, accept string }{
+		{"/", "application/json"},
+		{"/", "application/msgpack"},
+		{"/openapi.json", "application/json"},
+		{"/providers", "application/json"},
+	} {
+		first := get(tt.target, tt.accept)
+		for range 5 {
+			if again := get(tt.target, tt.accept); !bytes.Equal(first, again) {
+				t.Errorf("GET %s (%s) changed between identical requests:\n%s\n%s", tt.target, tt.accept, first, again)
+				break
+			}
+		}
+	}
+}
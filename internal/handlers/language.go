@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gin-gonic/gin"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+
+	"apppp/internal/scraper"
+)
+
+// supportedLanguages lists the Einthusan language slugs the API serves out
+// of the box. Requests are validated against knownLanguages, which adds any
+// that /languages has since discovered.
+var supportedLanguages = []string{"tamil", "hindi", "telugu", "malayalam", "kannada", "bengali", "marathi", "punjabi"}
+
+// defaultLanguage stands in for an omitted language on the browse endpoint.
+// It is empty, and so disabled, unless DEFAULT_LANGUAGE names a supported language.
+var defaultLanguage = loadDefaultLanguage()
+
+func loadDefaultLanguage() string {
+	language := strings.ToLower(strings.TrimSpace(Setting("DEFAULT_LANGUAGE")))
+	if language != "" && !slices.Contains(supportedLanguages, language) {
+		log.Printf("config: ignoring DEFAULT_LANGUAGE=%q, not a supported language", language)
+		return ""
+	}
+	return language
+}
+
+// requireLanguage reads the :language path param, lowercased so /search/Tamil
+// works. It responds 400 and returns false when the param is empty or not a
+// known language, so nothing unknown reaches an upstream URL. A near miss
+// such as "tamill" gets a suggestion.
+func requireLanguage(c *gin.Context) (string, bool) {
+	return checkLanguage(c, c.Param("language"))
+}
+
+// checkLanguage is requireLanguage for a language taken from somewhere other
+// than the :language param.
+func checkLanguage(c *gin.Context, raw string) (string, bool) {
+	language := strings.ToLower(strings.TrimSpace(raw))
+	if language == "" {
+		missingLanguage(c)
+		return "", false
+	}
+	if known := knownLanguages(); !slices.Contains(known, language) {
+		body := errorBody("unsupported_language", "unsupported language")
+		if suggestion := closestLanguage(language, known); suggestion != "" {
+			body = errorBody("unsupported_language", fmt.Sprintf("unsupported language %q; did you mean %q?", language, suggestion))
+			body["suggestion"] = suggestion
+		}
+		body["supported"] = known
+		abortWithError(c, http.StatusBadRequest, body)
+		return "", false
+	}
+	return language, true
+}
+
+// closestLanguage returns the known language within two edits of language,
+// or "" when none is that close.
+func closestLanguage(language string, known []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range known {
+		if d := fuzzy.LevenshteinDistance(language, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// missingLanguage answers routes hit with an empty language segment, such as /search/?q=theri.
+func missingLanguage(c *gin.Context) {
+	body := errorBody("language_required", "language is required")
+	body["supported"] = knownLanguages()
+	abortWithError(c, http.StatusBadRequest, body)
+}
+
+// browseLanguage is requireLanguage with DEFAULT_LANGUAGE filling in an empty param.
+func browseLanguage(c *gin.Context) (string, bool) {
+	if defaultLanguage != "" && strings.TrimSpace(c.Param("language")) == "" {
+		return defaultLanguage, true
+	}
+	return requireLanguage(c)
+}
+
+// languageList reads the optional comma-separated list in query parameter
+// param, used by endpoints that fan out across languages. It defaults to every
+// known language and responds 400 naming any entries that aren't known.
+// The value "all" also selects every known language.
+func languageList(c *gin.Context, param string) ([]string, bool) {
+	raw := strings.TrimSpace(c.Query(param))
+	known := knownLanguages()
+	if raw == "" || strings.EqualFold(raw, "all") {
+		return known, true
+	}
+	var languages, unknown []string
+	for _, part := range strings.Split(raw, ",") {
+		language := strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case language == "":
+			continue
+		case !slices.Contains(known, language):
+			unknown = append(unknown, part)
+		case !slices.Contains(languages, language):
+			languages = append(languages, language)
+		}
+	}
+	if len(unknown) > 0 {
+		body := errorBody("unsupported_language", "unsupported languages: "+strings.Join(unknown, ", "))
+		body["supported"] = known
+		abortWithError(c, http.StatusBadRequest, body)
+		return nil, false
+	}
+	if len(languages) == 0 {
+		return known, true
+	}
+	return languages, true
+}
+
+// Einthusan adds a language rarely, so the discovered list is kept for a day.
+const languagesTTL = 24 * time.Hour
+
+// LanguageInfo is one language Einthusan serves.
+type LanguageInfo struct {
+	Slug   string `json:"slug"` // The :language path value
+	Name   string `json:"name"`
+	Movies int    `json:"movies,omitempty"` // Catalog size, when the language picker shows it
+}
+
+type LanguagesResponse struct {
+	Languages []LanguageInfo `json:"languages"`
+	Source    string         `json:"source"` // "upstream", or "builtin" when Einthusan's picker couldn't be read
+}
+
+var languagesCache = newTTLCache[[]LanguageInfo](languagesTTL)
+
+// discoveredLanguages holds knownLanguages once a discovery has succeeded.
+var discoveredLanguages atomic.Pointer[[]string]
+
+// knownLanguages is what requests are validated against: supportedLanguages
+// plus any language the last /languages discovery found on Einthusan, so a
+// language it adds is served without a release. Validation never scrapes;
+// until something calls /languages it is supportedLanguages alone.
+func knownLanguages() []string {
+	if known := discoveredLanguages.Load(); known != nil {
+		return *known
+	}
+	return supportedLanguages
+}
+
+var (
+	languageSlugPattern  = regexp.MustCompile(`^[a-z]+$`)
+	languageCountPattern = regexp.MustCompile(`(?i)\b(\d[\d,]*)\s*(?:movies|films|titles)\b`)
+)
+
+// scrapeLanguages reads the languages on Einthusan's language picker, which
+// links each one by its lang= slug. The link text gives the display name and,
+// on some layouts, how many movies the language has.
+func scrapeLanguages(ctx context.Context) ([]LanguageInfo, error) {
+	if languages, ok := languagesCache.get(""); ok {
+		return languages, nil
+	}
+	res, err := fetchUpstream(ctx, scraper.Einthusan.BaseURL()+"/intro/")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var languages []LanguageInfo
+	doc.Find(`a[href*="lang="]`).Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		slug := strings.ToLower(u.Query().Get("lang"))
+		if !languageSlugPattern.MatchString(slug) || slices.ContainsFunc(languages, func(l LanguageInfo) bool { return l.Slug == slug }) {
+			return
+		}
+		// Join the link's text leaf by leaf, so a name and count in separate
+		// elements don't run together.
+		var parts []string
+		s.Find("*").Each(func(i int, e *goquery.Selection) {
+			if e.Children().Length() == 0 {
+				parts = append(parts, e.Text())
+			}
+		})
+		if len(parts) == 0 {
+			parts = append(parts, s.Text())
+		}
+		info := LanguageInfo{Slug: slug}
+		label := strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+		if m := languageCountPattern.FindStringSubmatch(label); m != nil {
+			info.Movies, _ = strconv.Atoi(strings.ReplaceAll(m[1], ",", ""))
+			label = strings.TrimSpace(strings.Replace(label, m[0], "", 1))
+		}
+		info.Name = label
+		if info.Name == "" {
+			info.Name = languageName(slug)
+		}
+		languages = append(languages, info)
+	})
+	if len(languages) == 0 {
+		return nil, fmt.Errorf("%w: no languages on the language picker", scraper.ErrParseFailed)
+	}
+
+	languagesCache.set("", languages)
+	known := slices.Clone(supportedLanguages)
+	for _, l := range languages {
+		if !slices.Contains(known, l.Slug) {
+			known = append(known, l.Slug)
+		}
+	}
+	discoveredLanguages.Store(&known)
+	return languages, nil
+}
+
+// languageName is a display name for a slug: "tamil" becomes "Tamil".
+func languageName(slug string) string {
+	return strings.ToUpper(slug[:1]) + slug[1:]
+}
+
+// listLanguages answers /languages. When Einthusan's picker can't be read
+// the built-in list is served instead, so clients always get something to
+// offer.
+func listLanguages(c *gin.Context) {
+	languages, err := scrapeLanguages(c.Request.Context())
+	if err == nil {
+		respond(c, http.StatusOK, LanguagesResponse{Languages: languages, Source: "upstream"})
+		return
+	}
+	slog.Warn("languages: discovery failed, serving the built-in list", "error", err)
+	resp := LanguagesResponse{Source: "builtin"}
+	for _, slug := range supportedLanguages {
+		resp.Languages = append(resp.Languages, LanguageInfo{Slug: slug, Name: languageName(slug)})
+	}
+	respond(c, http.StatusOK, resp)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEmptyLanguage(t *testing.T) {
+	for _, target := range []string{
+		"/search/?q=theri",
+		"/search/%20%20?q=theri",
+		"/search/%09?q=theri",
+		"/genre/",
+		"/genre/%20/action",
+		"/actors/%20/abc",
+		"/movie/%20/a1",
+	} {
+		t.Run(target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400; body %s", w.Code, w.Body)
+			}
+			var body struct {
+				Error     string   `json:"error"`
+				Code      string   `json:"code"`
+				Supported []string `json:"supported"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error != "language is required" || body.Code != "language_required" || len(body.Supported) == 0 {
+				t.Errorf("body = %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestMultiSearchLanguageSubset(t *testing.T) {
+	var mu sync.Mutex
+	var scraped []string
+	fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		scraped = append(scraped, r.URL.Query().Get("lang"))
+		mu.Unlock()
+		http.ServeFile(w, r, filepath.Join(fixtureDir, "results.html"))
+	})
+	tests := []struct {
+		languages   string
+		wantCode    int
+		wantScraped []string
+	}{
+		{"tamil,hindi", http.StatusOK, []string{"hindi", "tamil"}},
+		{" Tamil , ,tamil", http.StatusOK, []string{"tamil"}},
+		{"", http.StatusOK, knownLanguages()},
+		{"all", http.StatusOK, knownLanguages()},
+		{"tamil,klingon,elvish", http.StatusBadRequest, nil},
+	}
+	router := NewRouter()
+	for _, tt := range tests {
+		t.Run(tt.languages, func(t *testing.T) {
+			scraped = nil
+			cache.flush("", "")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q=theri&languages="+url.QueryEscape(tt.languages), nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantCode == http.StatusBadRequest {
+				var body struct {
+					Error string `json:"error"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &body)
+				if !strings.Contains(body.Error, "klingon") || !strings.Contains(body.Error, "elvish") || strings.Contains(body.Error, "tamil") {
+					t.Errorf("error %q should name exactly the unknown languages", body.Error)
+				}
+			}
+			slices.Sort(scraped)
+			want := slices.Sorted(slices.Values(tt.wantScraped))
+			if !slices.Equal(scraped, want) {
+				t.Errorf("scraped %v, want %v", scraped, want)
+			}
+		})
+	}
+}
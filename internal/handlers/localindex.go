@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+
+	"apppp/internal/models"
+)
+
+const (
+	defaultIndexSearchSize = 20
+	maxIndexSearchSize     = 100
+)
+
+// indexBucket holds one JSON IndexedMovie per "<language>/<id>".
+var indexBucket = []byte("movies")
+
+// IndexedMovie is a movie the server has seen in some scraped listing.
+type IndexedMovie struct {
+	models.MovieEntry
+	Language string    `json:"language"`
+	SeenAt   time.Time `json:"seen_at,omitzero"` // When a listing last showed it; unset on live results
+}
+
+type IndexSearchResponse struct {
+	Query   string         `json:"q"`
+	Movies  []IndexedMovie `json:"movies"`
+	Count   int            `json:"count"`
+	Source  string         `json:"source"`           // "index", or "live" when the index had nothing and Einthusan was searched
+	Indexed int            `json:"indexed"`          // Movies in the index
+	Reason  string         `json:"reason,omitempty"` // Why Movies is empty
+}
+
+// movieIndex is a local catalog of every movie seen in a scraped listing,
+// searchable by title words without reaching Einthusan. Every listing that
+// passes through the cache is added, so running with PREWARM_LANGUAGES keeps
+// the recent and popular listings crawled into it. With INDEX_DB set it is
+// kept in that bbolt file and survives restarts; otherwise it starts empty.
+type movieIndex struct {
+	mu     sync.RWMutex
+	movies map[string]IndexedMovie        // "<language>/<id>" -> movie
+	words  map[string]map[string]struct{} // title word -> movie keys
+	db     *bolt.DB
+}
+
+var localIndex = &movieIndex{movies: make(map[string]IndexedMovie), words: make(map[string]map[string]struct{})}
+
+// OpenIndex loads the index stored at path, creating the file if needed.
+// An empty path keeps the index in memory only.
+func OpenIndex(path string) error {
+	if path == "" {
+		return nil
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("index: open %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(indexBucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var m IndexedMovie
+			if err := json.Unmarshal(v, &m); err != nil {
+				return fmt.Errorf("entry %s: %w", k, err)
+			}
+			localIndex.put(string(k), m)
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("index: load %s: %w", path, err)
+	}
+	localIndex.db = db
+	return nil
+}
+
+// add records the movies of a scraped listing. Movies without an ID can't be
+// told apart reliably and are skipped.
+func (mi *movieIndex) add(language string, movies []models.MovieEntry) {
+	now := time.Now().UTC()
+	added := make(map[string]IndexedMovie, len(movies))
+	mi.mu.Lock()
+	for _, m := range movies {
+		if m.ID == "" {
+			continue
+		}
+		key := language + "/" + m.ID
+		entry := IndexedMovie{MovieEntry: m, Language: language, SeenAt: now}
+		// Listings differ in what they show, so keep fields a sparser one lacks.
+		if old, ok := mi.movies[key]; ok {
+			entry.Year = cmp.Or(entry.Year, old.Year)
+			entry.Duration = cmp.Or(entry.Duration, old.Duration)
+			entry.Synopsis = cmp.Or(entry.Synopsis, old.Synopsis)
+			entry.Views = cmp.Or(entry.Views, old.Views)
+		}
+		mi.put(key, entry)
+		added[key] = entry
+	}
+	db := mi.db
+	mi.mu.Unlock()
+	if db == nil || len(added) == 0 {
+		return
+	}
+	// Batch coalesces the writes of concurrent scrapes into one transaction.
+	db.Batch(func(tx *bolt.Tx) error {
+		b := tx.Bucket(indexBucket)
+		for key, entry := range added {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(key), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// put stores m under key and indexes its title words. Callers hold mu.
+func (mi *movieIndex) put(key string, m IndexedMovie) {
+	if old, ok := mi.movies[key]; ok {
+		for _, word := range strings.Fields(normalizeTitle(old.Title)) {
+			delete(mi.words[word], key)
+		}
+	}
+	mi.movies[key] = m
+	for _, word := range strings.Fields(normalizeTitle(m.Title)) {
+		if mi.words[word] == nil {
+			mi.words[word] = make(map[string]struct{})
+		}
+		mi.words[word][key] = struct{}{}
+	}
+}
+
+// search returns the movies in language (or any language when empty) whose
+// title has a word starting with each word of query, best match first.
+// strict is rankBy's.
+func (mi *movieIndex) search(language, query string, strict bool) []IndexedMovie {
+	terms := strings.Fields(normalizeTitle(query))
+	if len(terms) == 0 {
+		return nil
+	}
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+	var matches map[string]struct{}
+	for _, term := range terms {
+		found := make(map[string]struct{})
+		for word, keys := range mi.words {
+			if !strings.HasPrefix(word, term) {
+				continue
+			}
+			for key := range keys {
+				if _, ok := matches[key]; ok || matches == nil {
+					found[key] = struct{}{}
+				}
+			}
+		}
+		matches = found
+		if len(matches) == 0 {
+			return nil
+		}
+	}
+	results := make([]IndexedMovie, 0, len(matches))
+	for key := range matches {
+		if m := mi.movies[key]; language == "" || m.Language == language {
+			results = append(results, m)
+		}
+	}
+	// Map order is random; settle it before ranking so ties come out the same every time.
+	slices.SortFunc(results, func(a, b IndexedMovie) int {
+		return cmp.Or(cmp.Compare(a.Language, b.Language), cmp.Compare(a.ID, b.ID))
+	})
+	rankBy(query, results, func(m IndexedMovie) string { return m.Title }, strict)
+	return results
+}
+
+func (mi *movieIndex) size() int {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+	return len(mi.movies)
+}
+
+// searchIndex answers /index/search from the local index. When the index
+// has nothing and language= names one language, Einthusan is searched live
+// instead, which also adds what it finds to the index.
+func searchIndex(c *gin.Context) {
+	query := strings.Join(strings.Fields(c.Query("q")), " ")
+	if query == "" {
+		respondError(c, http.StatusBadRequest, "invalid_query", "q is required")
+		return
+	}
+	language := ""
+	if raw := c.Query("language"); raw != "" {
+		var ok bool
+		if language, ok = checkLanguage(c, raw); !ok {
+			return
+		}
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultIndexSearchSize)))
+	if err != nil || limit < 1 || limit > maxIndexSearchSize {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", fmt.Sprintf("limit must be between 1 and %d", maxIndexSearchSize))
+		return
+	}
+	full, ok := wantsFullFields(c)
+	if !ok {
+		return
+	}
+	strict, ok := wantsStrict(c)
+	if !ok {
+		return
+	}
+
+	resp := IndexSearchResponse{Query: query, Source: "index", Movies: localIndex.search(language, query, strict)}
+	if len(resp.Movies) == 0 && language != "" {
+		source, ok := requireProvider(c, language)
+		if !ok {
+			return
+		}
+		live, err := rankedSearch(c.Request.Context(), source, language, query, 1, searchOptions{order: "relevance", strict: strict})
+		if err != nil {
+			respondScrapeError(c, err)
+			return
+		}
+		resp.Source = "live"
+		for _, m := range live.Movies {
+			resp.Movies = append(resp.Movies, IndexedMovie{MovieEntry: m, Language: language})
+		}
+	}
+	resp.Movies = resp.Movies[:min(limit, len(resp.Movies))]
+	if !full {
+		for i := range resp.Movies {
+			resp.Movies[i].Duration, resp.Movies[i].Synopsis, resp.Movies[i].Views = "", "", 0
+		}
+	}
+	if resp.Movies == nil {
+		resp.Movies = []IndexedMovie{}
+	}
+	resp.Count = len(resp.Movies)
+	resp.Indexed = localIndex.size()
+	if resp.Count == 0 {
+		resp.Reason = reasonNoMatches
+	}
+	respond(c, http.StatusOK, resp)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigureLogging switches every log line, including the standard log
+// package's, to JSON on stderr at the LOG_LEVEL threshold (debug, info, warn
+// or error; info by default).
+func ConfigureLogging(raw string) {
+	var level slog.Level
+	if raw != "" {
+		if err := level.UnmarshalText([]byte(raw)); err != nil {
+			defer log.Printf("config: ignoring invalid LOG_LEVEL=%q: %v", raw, err)
+		}
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
+type requestIDKey struct{}
+
+// requestLogger replaces gin's text access log with one structured line per
+// request, including how its listings were served.
+//
+// Each request gets an ID, echoed in X-Request-ID and attached to every
+// upstream scrape logged on its behalf, so a client's report can be traced to
+// the scrapes behind it. A well-formed X-Request-ID from the client, such as
+// one set by a proxy in front, is kept rather than replaced.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header("X-Request-ID", id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+		c.Next()
+		slog.Info("request",
+			"request_id", id,
+			"route", c.FullPath(),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"query", c.Request.URL.RawQuery,
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"cache", scopeFrom(c.Request.Context()).cacheStatus(),
+		)
+	}
+}
+
+// requestID returns the ID requestLogger gave the request behind ctx, or ""
+// outside of a request.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts short printable IDs only, so a client can't stuff
+// arbitrary text into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
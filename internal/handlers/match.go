@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const (
+	defaultTMDBApiUrl = "https://api.themoviedb.org/3"
+	tmdbImageBase     = "https://image.tmdb.org/t/p/original"
+	// minMatchSimilarity is how alike a TMDB title must be to the scraped one
+	// to count as a match at all.
+	minMatchSimilarity = 0.6
+	matchTTL           = 24 * time.Hour
+)
+
+// TMDB access is configured by TMDB_API_KEY; TMDB_API_URL points it at
+// another base URL, e.g. a caching proxy.
+var (
+	tmdbApiKey = Setting("TMDB_API_KEY")
+	tmdbApiUrl = strings.TrimRight(cmp.Or(Setting("TMDB_API_URL"), defaultTMDBApiUrl), "/")
+	tmdbClient = &http.Client{Timeout: upstreamTimeout}
+)
+
+// MatchResponse joins an Einthusan movie to its canonical metadata.
+type MatchResponse struct {
+	ID         string   `json:"id"`
+	Language   string   `json:"language"`
+	Provider   string   `json:"provider"`
+	Title      string   `json:"title"` // as scraped from Einthusan
+	Year       int      `json:"year,omitempty"`
+	TMDBID     int      `json:"tmdb_id"`
+	IMDbID     string   `json:"imdb_id,omitempty"`
+	MatchTitle string   `json:"match_title"` // the provider's title
+	Confidence float64  `json:"confidence"`  // title similarity, 0 to 1
+	Overview   string   `json:"overview,omitempty"`
+	Rating     float64  `json:"rating,omitempty"` // provider's average vote out of 10
+	VoteCount  int      `json:"vote_count,omitempty"`
+	Poster     string   `json:"poster,omitempty"`
+	Backdrops  []string `json:"backdrops"`
+}
+
+var (
+	errMatchNotConfigured = errors.New("TMDB_API_KEY is not set")
+	errNoMatch            = errors.New("no matching movie found")
+)
+
+var matchCache = newTTLCache[*MatchResponse](matchTTL)
+
+// matchMovie resolves a scraped movie against an external metadata provider.
+// Only tmdb is supported; IMDb IDs come from TMDB's external IDs.
+func matchMovie(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	provider := strings.ToLower(c.DefaultQuery("provider", "tmdb"))
+	if provider != "tmdb" {
+		respondError(c, http.StatusBadRequest, "invalid_provider", "provider must be tmdb")
+		return
+	}
+	if tmdbApiKey == "" {
+		respondError(c, http.StatusNotImplemented, "match_not_configured", errMatchNotConfigured.Error())
+		return
+	}
+	id := c.Param("movieid")
+	key := language + "/" + id
+	if match, ok := matchCache.get(key); ok {
+		respond(c, http.StatusOK, match)
+		return
+	}
+	detail, err := upstream.ScrapeMovieDetail(c.Request.Context(), language, id)
+	if errors.Is(err, scraper.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+		return
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	match, err := matchTMDB(c.Request.Context(), detail)
+	if errors.Is(err, errNoMatch) {
+		respondError(c, http.StatusNotFound, "no_match", err.Error())
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusBadGateway, "provider_error", err.Error())
+		return
+	}
+	matchCache.set(key, match)
+	respond(c, http.StatusOK, match)
+}
+
+type tmdbSearchResult struct {
+	ID            int    `json:"id"`
+	Title         string `json:"title"`
+	OriginalTitle string `json:"original_title"`
+	ReleaseDate   string `json:"release_date"`
+}
+
+// matchTMDB searches TMDB for the detail's title, restricted to its release
+// year when known, and takes the most similar title. The winner's full record
+// supplies the IMDb ID, rating and images.
+func matchTMDB(ctx context.Context, detail *models.MovieDetail) (*MatchResponse, error) {
+	query := url.Values{"query": {detail.Title}}
+	if detail.Year > 0 {
+		query.Set("primary_release_year", strconv.Itoa(detail.Year))
+	}
+	var search struct {
+		Results []tmdbSearchResult `json:"results"`
+	}
+	if err := tmdbGet(ctx, "/search/movie", query, &search); err != nil {
+		return nil, err
+	}
+	var best tmdbSearchResult
+	bestScore := 0.0
+	for _, r := range search.Results {
+		score := max(titleSimilarity(detail.Title, r.Title), titleSimilarity(detail.Title, r.OriginalTitle))
+		if score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	if bestScore < minMatchSimilarity {
+		return nil, errNoMatch
+	}
+
+	var movie struct {
+		Title       string  `json:"title"`
+		Overview    string  `json:"overview"`
+		VoteAverage float64 `json:"vote_average"`
+		VoteCount   int     `json:"vote_count"`
+		PosterPath  string  `json:"poster_path"`
+		ExternalIDs struct {
+			IMDbID string `json:"imdb_id"`
+		} `json:"external_ids"`
+		Images struct {
+			Backdrops []struct {
+				FilePath string `json:"file_path"`
+			} `json:"backdrops"`
+		} `json:"images"`
+	}
+	if err := tmdbGet(ctx, fmt.Sprintf("/movie/%d", best.ID), url.Values{"append_to_response": {"external_ids,images"}}, &movie); err != nil {
+		return nil, err
+	}
+	match := &MatchResponse{
+		ID:         detail.ID,
+		Language:   detail.Language,
+		Provider:   "tmdb",
+		Title:      detail.Title,
+		Year:       detail.Year,
+		TMDBID:     best.ID,
+		IMDbID:     movie.ExternalIDs.IMDbID,
+		MatchTitle: movie.Title,
+		Confidence: bestScore,
+		Overview:   movie.Overview,
+		Rating:     movie.VoteAverage,
+		VoteCount:  movie.VoteCount,
+		Backdrops:  []string{},
+	}
+	if movie.PosterPath != "" {
+		match.Poster = tmdbImageBase + movie.PosterPath
+	}
+	for _, b := range movie.Images.Backdrops {
+		match.Backdrops = append(match.Backdrops, tmdbImageBase+b.FilePath)
+	}
+	return match, nil
+}
+
+func tmdbGet(ctx context.Context, path string, query url.Values, out any) error {
+	query.Set("api_key", tmdbApiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tmdbApiUrl+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := tmdbClient.Do(req)
+	if err != nil {
+		// The URL carries the API key; keep it out of error messages.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("tmdb: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("tmdb returned %s", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
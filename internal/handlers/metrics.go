@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+// latencyBuckets are the histogram upper bounds, in seconds. Scrapes take
+// hundreds of milliseconds to seconds, cache hits a few milliseconds.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(seconds float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets))
+	}
+	if i, _ := slices.BinarySearch(latencyBuckets, seconds); i < len(latencyBuckets) {
+		h.counts[i]++
+	}
+	h.sum += seconds
+	h.count++
+}
+
+type requestKey struct {
+	method, route, status string
+}
+
+// metricsRegistry holds everything /metrics reports. It is written in the
+// Prometheus text format by hand, as the handful of series here don't
+// warrant the client library.
+type metricsRegistry struct {
+	mu             sync.Mutex
+	requests       map[requestKey]uint64
+	latency        map[string]*histogram // by route
+	scrapes        histogram
+	scrapeFailures uint64
+	scrapeDegraded uint64
+	blocked        map[string]uint64 // by block reason
+	lastScrapeOK   time.Time         // when a listing scrape last succeeded
+}
+
+var metrics = &metricsRegistry{requests: make(map[requestKey]uint64), latency: make(map[string]*histogram), blocked: make(map[string]uint64)}
+
+// instrumentRequests counts every request and times it by route template,
+// so new routes are covered without any per-handler code. Requests that
+// match no route are grouped under "unmatched" to keep the series bounded.
+func instrumentRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		metrics.requests[requestKey{c.Request.Method, route, strconv.Itoa(c.Writer.Status())}]++
+		h, ok := metrics.latency[route]
+		if !ok {
+			h = &histogram{}
+			metrics.latency[route] = h
+		}
+		h.observe(time.Since(start).Seconds())
+	}
+}
+
+// observeScrape records one upstream listing scrape.
+func (m *metricsRegistry) observeScrape(d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scrapes.observe(d.Seconds())
+	if err != nil {
+		m.scrapeFailures++
+		return
+	}
+	m.lastScrapeOK = time.Now()
+}
+
+// observeDegraded records a scrape that parsed to no movies though the page
+// listed some.
+func (m *metricsRegistry) observeDegraded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scrapeDegraded++
+}
+
+// observeBlocked records an upstream block page of the given reason.
+func (m *metricsRegistry) observeBlocked(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blocked[reason]++
+}
+
+// lastSuccessfulScrape is when a listing scrape last succeeded, zero if none has.
+func (m *metricsRegistry) lastSuccessfulScrape() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastScrapeOK
+}
+
+func (m *metricsRegistry) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP http_requests_total Requests served, by method, route and status.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b requestKey) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method), cmp.Compare(a.status, b.status))
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "http_requests_total{method=%q,route=%q,status=%q} %d\n", k.method, k.route, k.status, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Request latency by route.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	routes := make([]string, 0, len(m.latency))
+	for route := range m.latency {
+		routes = append(routes, route)
+	}
+	slices.Sort(routes)
+	for _, route := range routes {
+		writeHistogram(w, "http_request_duration_seconds", fmt.Sprintf("route=%q,", route), m.latency[route])
+	}
+
+	fmt.Fprintln(w, "# HELP upstream_scrape_duration_seconds Time taken by upstream listing scrapes.")
+	fmt.Fprintln(w, "# TYPE upstream_scrape_duration_seconds histogram")
+	writeHistogram(w, "upstream_scrape_duration_seconds", "", &m.scrapes)
+	fmt.Fprintln(w, "# HELP upstream_scrape_errors_total Upstream listing scrapes that failed.")
+	fmt.Fprintln(w, "# TYPE upstream_scrape_errors_total counter")
+	fmt.Fprintf(w, "upstream_scrape_errors_total %d\n", m.scrapeFailures)
+	fmt.Fprintln(w, "# HELP upstream_scrape_degraded_total Listing scrapes whose selectors matched nothing on a page that had results.")
+	fmt.Fprintln(w, "# TYPE upstream_scrape_degraded_total counter")
+	fmt.Fprintf(w, "upstream_scrape_degraded_total %d\n", m.scrapeDegraded)
+	fmt.Fprintln(w, "# HELP upstream_blocked_total Upstream responses that were a challenge, CAPTCHA, geo-block or ban page, by reason.")
+	fmt.Fprintln(w, "# TYPE upstream_blocked_total counter")
+	for _, reason := range scraper.BlockReasons {
+		fmt.Fprintf(w, "upstream_blocked_total{reason=%q} %d\n", reason, m.blocked[reason])
+	}
+
+	fmt.Fprintln(w, "# HELP upstream_circuit_state Whether the upstream circuit breaker is closed, open or half-open (probing).")
+	fmt.Fprintln(w, "# TYPE upstream_circuit_state gauge")
+	state := breaker.state()
+	for _, s := range []string{"closed", "open", "half_open"} {
+		current := 0
+		if s == state {
+			current = 1
+		}
+		fmt.Fprintf(w, "upstream_circuit_state{state=%q} %d\n", s, current)
+	}
+
+	fmt.Fprintln(w, "# HELP upstream_requests_in_flight Upstream requests the scheduler has let through and not yet finished.")
+	fmt.Fprintln(w, "# TYPE upstream_requests_in_flight gauge")
+	fmt.Fprintf(w, "upstream_requests_in_flight %d\n", scheduler.inFlight.Load())
+	fmt.Fprintln(w, "# HELP upstream_requests_queued Upstream requests waiting for the scheduler.")
+	fmt.Fprintln(w, "# TYPE upstream_requests_queued gauge")
+	fmt.Fprintf(w, "upstream_requests_queued %d\n", scheduler.queued.Load())
+
+	snap := stats.snapshot()
+	fmt.Fprintln(w, "# HELP listing_lookups_total Listing lookups, by how they were served.")
+	fmt.Fprintln(w, "# TYPE listing_lookups_total counter")
+	fmt.Fprintf(w, "listing_lookups_total{source=\"upstream\"} %d\n", snap.UpstreamScrapes)
+	fmt.Fprintf(w, "listing_lookups_total{source=\"cache\"} %d\n", snap.CacheHits)
+	fmt.Fprintf(w, "listing_lookups_total{source=\"request_memo\"} %d\n", snap.RequestMemoHits)
+	fmt.Fprintf(w, "listing_lookups_total{source=\"coalesced\"} %d\n", snap.CoalescedScrapes)
+	fmt.Fprintln(w, "# HELP cache_hit_ratio Share of listing lookups served without an upstream scrape.")
+	fmt.Fprintln(w, "# TYPE cache_hit_ratio gauge")
+	fmt.Fprintf(w, "cache_hit_ratio %g\n", snap.SavedRatio)
+}
+
+// writeHistogram writes h with cumulative buckets; labels, when set, must
+// end in a comma so le can follow.
+func writeHistogram(w io.Writer, name, labels string, h *histogram) {
+	var cumulative uint64
+	for i, bound := range latencyBuckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%g\"} %d\n", name, labels, bound, cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+	if labels != "" {
+		labels = "{" + labels[:len(labels)-1] + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+func showMetrics(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.write(c.Writer)
+}
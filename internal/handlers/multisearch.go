@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+// MultiSearchResponse is a search run across several languages at once. A
+// film found in more than one language appears once, listing them all.
+type MultiSearchResponse struct {
+	Query     string            `json:"q"`
+	Languages []string          `json:"languages"`
+	Movies    []CombinedMovie   `json:"movies"`
+	Page      int               `json:"page"`
+	NextPage  int               `json:"next_page"`
+	HasMore   bool              `json:"has_more"`
+	Counts    map[string]int    `json:"counts"`           // Movies each language contributed
+	Errors    map[string]string `json:"errors,omitempty"` // Languages that failed, with why
+	Reason    string            `json:"reason,omitempty"` // Why Movies is empty
+}
+
+// multiSearch searches every language in languages= (all supported languages
+// by default, or with languages=all) concurrently and merges the results;
+// langs= is accepted as an older spelling. Titles at least similarity=
+// alike (TITLE_SIMILARITY by default) are folded together. A language that
+// fails is reported in errors; the request only fails when all of them do.
+// provider= picks the source, which must serve every language asked for.
+func multiSearch(c *gin.Context) {
+	param := "languages"
+	if c.Query(param) == "" && c.Query("langs") != "" {
+		param = "langs"
+	}
+	languages, ok := languageList(c, param)
+	if !ok {
+		return
+	}
+	source, ok := lookupProvider(c)
+	if !ok {
+		return
+	}
+	for _, language := range languages {
+		if !providerServes(c, source, language) {
+			return
+		}
+	}
+	query := strings.Join(strings.Fields(c.Query("q")), " ")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	similarity := titleSimilarityDefault
+	if raw := c.Query("similarity"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v > 1 {
+			respondError(c, http.StatusBadRequest, "invalid_parameter", "similarity must be a number between 0 and 1")
+			return
+		}
+		similarity = v
+	}
+
+	if query == "" {
+		respondError(c, http.StatusBadRequest, "invalid_query", "q is required")
+		return
+	}
+	full, ok := wantsFullFields(c)
+	if !ok {
+		return
+	}
+	strict, ok := wantsStrict(c)
+	if !ok {
+		return
+	}
+	resp := MultiSearchResponse{Query: query, Languages: languages, Movies: []CombinedMovie{}, Page: page, Counts: make(map[string]int, len(languages))}
+
+	results := make([]scraper.Listing, len(languages))
+	errs := make([]error, len(languages))
+	sem := make(chan struct{}, maxConcurrentPages)
+	var wg sync.WaitGroup
+	for i, language := range languages {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = source.search(c.Request.Context(), language, query, page)
+		})
+	}
+	wg.Wait()
+
+	var combined []CombinedMovie
+	for i, language := range languages {
+		if errs[i] != nil {
+			if resp.Errors == nil {
+				resp.Errors = make(map[string]string)
+			}
+			resp.Errors[language] = errs[i].Error()
+			continue
+		}
+		resp.HasMore = resp.HasMore || results[i].HasNext
+		resp.Counts[language] = len(results[i].Movies)
+		if !full {
+			basicFields(results[i].Movies)
+		}
+		for _, m := range results[i].Movies {
+			combined = append(combined, CombinedMovie{MovieEntry: m, Languages: []string{language}})
+		}
+	}
+	if len(resp.Errors) == len(languages) {
+		respondScrapeError(c, errs[0])
+		return
+	}
+
+	canonical, _ := queryVariants(query)
+	resp.Movies = mergeSimilarTitles(combined, similarity)
+	rankBy(canonical, resp.Movies, func(m CombinedMovie) string { return m.Title }, strict)
+	resp.NextPage = nextPageAfter(page, resp.HasMore)
+	if len(resp.Movies) == 0 {
+		resp.Reason = reasonNoMatches
+	}
+	respond(c, http.StatusOK, resp)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"cmp"
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// movieNFO is a Kodi movie NFO document, which Jellyfin and Emby read too.
+// See https://kodi.wiki/view/NFO_files/Movies.
+type movieNFO struct {
+	XMLName  xml.Name    `xml:"movie"`
+	Title    string      `xml:"title"`
+	Year     int         `xml:"year,omitempty"`
+	Plot     string      `xml:"plot,omitempty"`
+	Runtime  int         `xml:"runtime,omitempty"` // Minutes
+	Thumbs   []nfoThumb  `xml:"thumb"`
+	Genres   []string    `xml:"genre"`
+	Director string      `xml:"director,omitempty"`
+	Actors   []nfoActor  `xml:"actor"`
+	Trailer  string      `xml:"trailer,omitempty"`
+	UniqueID nfoUniqueID `xml:"uniqueid"`
+}
+
+type nfoThumb struct {
+	Aspect string `xml:"aspect,attr"`
+	URL    string `xml:",chardata"`
+}
+
+type nfoActor struct {
+	Name  string `xml:"name"`
+	Order int    `xml:"order"`
+	Thumb string `xml:"thumb,omitempty"`
+}
+
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	ID      string `xml:",chardata"`
+}
+
+var (
+	runtimeHoursPattern   = regexp.MustCompile(`(?i)(\d+)\s*h`)
+	runtimeMinutesPattern = regexp.MustCompile(`(?i)(\d+)\s*m`)
+)
+
+// writeNFO answers /movie/:language/:id.nfo with detail as an NFO file, for
+// library managers importing metadata. The trailer is given as a link for
+// Kodi's YouTube add-on, which is how Kodi expects YouTube trailers.
+func writeNFO(c *gin.Context, detail *models.MovieDetail) {
+	nfo := movieNFO{
+		Title:    detail.Title,
+		Year:     detail.Year,
+		Plot:     detail.Synopsis,
+		Runtime:  runtimeMinutes(detail.Duration),
+		Genres:   detail.Genres,
+		Director: detail.Director,
+		UniqueID: nfoUniqueID{Type: "einthusan", Default: true, ID: detail.ID},
+	}
+	if poster := cmp.Or(detail.PosterHD, detail.ImgUrl); poster != "" {
+		nfo.Thumbs = append(nfo.Thumbs, nfoThumb{Aspect: "poster", URL: poster})
+	}
+	for i, member := range detail.Cast {
+		nfo.Actors = append(nfo.Actors, nfoActor{Name: member.Name, Order: i})
+	}
+	if match := scraper.YoutubeIDPattern.FindStringSubmatch(detail.Trailer); match != nil {
+		nfo.Trailer = "plugin://plugin.video.youtube/?action=play_video&videoid=" + match[1]
+	}
+	c.Header("Content-Disposition", "inline; filename="+strconv.Quote(detail.ID+".nfo"))
+	writeXML(c, "application/xml; charset=utf-8", nfo)
+}
+
+// runtimeMinutes reads a runtime such as "2h 38m" or "95 mins" as minutes,
+// or 0 when there is none.
+func runtimeMinutes(duration string) int {
+	minutes := 0
+	rest := duration
+	if match := runtimeHoursPattern.FindStringSubmatchIndex(duration); match != nil {
+		hours, _ := strconv.Atoi(duration[match[2]:match[3]])
+		minutes = hours * 60
+		rest = duration[match[1]:]
+	}
+	if match := runtimeMinutesPattern.FindStringSubmatch(strings.TrimSpace(rest)); match != nil {
+		m, _ := strconv.Atoi(match[1])
+		minutes += m
+	}
+	return minutes
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+// apiRoute documents one endpoint. The response schemas are derived from the
+// Go types by reflection, so the spec can't drift from what the handlers
+// actually encode; only this table of routes and parameters is kept by hand.
+type apiRoute struct {
+	method, path, summary string
+	query                 []apiParam
+	response              any // zero value of the 200 response type, nil for non-JSON bodies
+	errors                []int
+}
+
+type apiParam struct {
+	name, typ, description string
+	required               bool
+}
+
+var (
+	pageParam          = apiParam{"page", "integer", "Upstream page to start from (default 1).", false}
+	pageSizeParam      = apiParam{"page_size", "integer", "Keep taking whole pages until at least this many movies are collected.", false}
+	enrichParam        = apiParam{"enrich", "boolean", "Add trailer_url and poster_hd from each movie's own page.", false}
+	pagesParam         = apiParam{"pages", "string", "Fetch several pages concurrently: a count from page, or a range like 1-3.", false}
+	fieldsParam        = apiParam{"fields", "string", "basic (default), full, which adds duration, synopsis and views, or a comma-separated list of movie fields to keep, such as id,title.", false}
+	providerParam      = apiParam{"provider", "string", "Source site to read from; see /providers (default einthusan).", false}
+	einthusanOnlyParam = apiParam{"provider", "string", "Only einthusan, the default, serves this route.", false}
+	windowParam        = apiParam{"window", "string", "For category=popular, count views over the last week, month, year, or alltime (the default).", false}
+	cursorParam        = apiParam{"cursor", "string", "A previous response's next_cursor; replaces page and the listing's own parameters.", false}
+	strictParam        = apiParam{"strict", "boolean", "Rank by titles as written, without folding transliterated spellings such as Theri and Thiri together.", false}
+)
+
+var apiRoutes = []apiRoute{
+	{"GET", "/search/:language", "Search one language", []apiParam{{"q", "string", "Movie title to search for.", true}, pageParam, {"min_score", "integer", "Keep only titles within this edit distance of q; 0 keeps exact matches.", false}, {"limit", "integer", "Return at most this many results.", false}, {"sort", "string", "relevance (default), title, or year (newest first).", false}, strictParam, enrichParam, fieldsParam, providerParam}, models.SearchResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/index/search", "Search movies already seen in scraped listings, falling back to a live search", []apiParam{{"q", "string", "Title words; each must start a word of the title.", true}, {"language", "string", "Only this language; also enables the live fallback.", false}, {"limit", "integer", "Movies to return, 1-100 (default 20).", false}, strictParam, fieldsParam}, IndexSearchResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/search", "Search several languages at once", []apiParam{{"q", "string", "Movie title to search for.", true}, {"languages", "string", "Comma-separated languages, or all (the default).", false}, {"similarity", "number", "How alike two titles must be to merge, 0 to 1.", false}, strictParam, pageParam, fieldsParam, providerParam}, MultiSearchResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/language/:language", "Browse recent or popular movies", []apiParam{{"category", "string", "recent (default) or popular.", false}, windowParam, pageParam, pageSizeParam, pagesParam, enrichParam, fieldsParam, providerParam}, models.BrowseResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/trending/:language", "Popular and recent first pages in one call", []apiParam{providerParam}, TrendingResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/actors/:language/:actorcode", "An actor's filmography", []apiParam{pageParam, pageSizeParam, pagesParam, fieldsParam, providerParam}, models.ActorResponse{}, []int{400, 404, 451, 502, 503, 504}},
+	{"GET", "/genre/:language", "Browse by per-aspect ratings", []apiParam{{"action", "integer", "0-4", false}, {"comedy", "integer", "0-4", false}, {"romance", "integer", "0-4", false}, {"storyline", "integer", "0-4", false}, {"performance", "integer", "0-4", false}, {"ratecount", "integer", "Minimum number of ratings.", false}, pageParam, pageSizeParam, fieldsParam, einthusanOnlyParam}, models.BrowseResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/genre/:language/:genre", "Browse a named genre", []apiParam{pageParam, pageSizeParam, fieldsParam, einthusanOnlyParam}, GenreResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/decade/:language/:decade", "Browse a decade", []apiParam{pageParam, pageSizeParam, fieldsParam, einthusanOnlyParam}, models.BrowseResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/year/:language/:year", "Browse a release year", []apiParam{pageParam, pageSizeParam, fieldsParam, einthusanOnlyParam}, models.BrowseResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/watch", "Resolve the stream link for a movie", []apiParam{{"url", "string", "Einthusan watch page URL, on any mirror or domain.", false}, {"id", "string", "Movie ID, instead of url.", false}, {"language", "string", "Language of id, or of a url without lang.", false}}, WatchResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/available/:language/:id", "Whether a movie can currently be played", nil, AvailabilityResponse{}, []int{400, 404, 451, 502, 503, 504}},
+	{"GET", "/filters/:language", "Finder controls available for a language", nil, FiltersResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/changes/:language", "Recent listing changes since the last snapshot", nil, ChangesResponse{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/img", "Proxy an Einthusan image", []apiParam{{"url", "string", "Einthusan CDN image URL.", true}, {"w", "integer", "Scale down to this width (16-1280) and re-encode as JPEG.", false}}, nil, []int{400, 502}},
+	{"GET", "/export", "Stream the catalog as NDJSON, one ExportBlock per line", []apiParam{{"pages", "integer", "Pages per language.", false}, {"languages", "string", "Comma-separated languages, or all (the default).", false}}, nil, []int{400}},
+	{"GET", "/movie/:language/:id", "Movie details; an id ending in .nfo returns them as a Kodi NFO file", []apiParam{providerParam}, models.MovieDetail{}, []int{400, 404, 451, 502, 503, 504}},
+	{"GET", "/similar/:language/:movieid", "Movies sharing cast or genres, best match first", []apiParam{{"limit", "integer", "Movies to return, 1-50 (default 20).", false}, fieldsParam, providerParam}, SimilarResponse{}, []int{400, 404, 451, 502, 503, 504}},
+	{"POST", "/movies/batch", "Details for up to 50 movies (JSON array of page URLs or IDs body)", []apiParam{{"language", "string", "Language of bare movie IDs.", false}, providerParam}, BatchResponse{}, []int{400, 422}},
+	{"GET", "/subtitles/:language/:movieid", "A movie's subtitle file, proxied from Einthusan", []apiParam{{"lang", "string", "Subtitle language, as listed in the movie's subtitles (default the first).", false}, {"format", "string", "srt or vtt; converts when Einthusan serves the other.", false}, providerParam}, nil, []int{400, 404, 451, 502, 503, 504}},
+	{"GET", "/stream/:language/:movieid", "Playable stream links", nil, StreamResponse{}, []int{400, 404, 451, 501, 502, 503, 504}},
+	{"GET", "/match/:language/:movieid", "Match a movie against TMDB", []apiParam{{"provider", "string", "Metadata provider; only tmdb.", false}}, MatchResponse{}, []int{400, 404, 451, 501, 502, 503, 504}},
+	{"GET", "/feed/:file", "Recent releases as a feed; file is the language plus .rss or .atom", nil, nil, []int{400, 404, 451, 502, 503, 504}},
+	{"GET", "/playlist/:file", "Playable M3U playlist of a listing; file is the language plus .m3u or .m3u8", []apiParam{{"category", "string", "recent (default) or popular.", false}, {"limit", "integer", "Movies to include, 1-50 (default 20).", false}, providerParam}, nil, []int{400, 404, 451, 501, 502, 503, 504}},
+	{"GET", "/manifest.json", "Stremio addon manifest; catalog, meta and stream resources follow the Stremio addon protocol", nil, nil, nil},
+	{"POST", "/graphql", "GraphQL over search, browse, actors and movie details (JSON body with query and variables)", nil, nil, []int{400}},
+	{"GET", "/events/:language", "Server-Sent Events stream of release events (JSON ReleaseEvent) for movies new to the recent listing; PREWARM_LANGUAGES only", nil, nil, []int{400, 404}},
+	{"GET", "/watchlist", "Saved movies, most recent first", nil, WatchlistResponse{}, []int{501}},
+	{"POST", "/watchlist", "Save a movie (JSON WatchlistEntry body)", nil, WatchlistEntry{}, []int{400, 422, 501}},
+	{"DELETE", "/watchlist/:id", "Remove a saved movie", nil, nil, []int{404, 501}},
+	{"POST", "/downloads", "Queue a movie to download into DOWNLOAD_DIR (JSON DownloadRequest body); answers 202", nil, Download{}, []int{400, 409, 422, 501, 503}},
+	{"GET", "/downloads", "Queued, running and finished downloads, newest first", nil, DownloadsResponse{}, []int{501}},
+	{"GET", "/downloads/:id", "One download's status and progress", nil, Download{}, []int{404, 501}},
+	{"DELETE", "/downloads/:id", "Cancel a download, or drop a finished one from the list", nil, nil, []int{404, 501}},
+	{"GET", "/v2/search/:language", "Search one language, as a ListPage", []apiParam{{"q", "string", "Movie title to search for; not needed with cursor.", false}, cursorParam, pageParam, {"min_score", "integer", "Keep only titles within this edit distance of q; 0 keeps exact matches.", false}, {"limit", "integer", "Return at most this many results.", false}, {"sort", "string", "relevance (default), title, or year (newest first).", false}, strictParam, enrichParam, fieldsParam, providerParam}, ListPage{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/v2/language/:language", "Browse recent or popular movies, as a ListPage", []apiParam{{"category", "string", "recent (default) or popular; not needed with cursor.", false}, windowParam, cursorParam, pageParam, pageSizeParam, pagesParam, enrichParam, fieldsParam, providerParam}, ListPage{}, []int{400, 451, 502, 503, 504}},
+	{"GET", "/v2/actors/:language/:actorcode", "An actor's filmography, as a ListPage", []apiParam{cursorParam, pageParam, pageSizeParam, pagesParam, fieldsParam, providerParam}, ListPage{}, []int{400, 404, 451, 502, 503, 504}},
+	{"GET", "/languages", "Languages Einthusan serves, with display names and catalog sizes where shown", nil, LanguagesResponse{}, nil},
+	{"GET", "/providers", "Registered source sites", nil, ProvidersResponse{}, nil},
+	{"GET", "/usage", "Today's quota for the calling X-API-Key", nil, UsageResponse{}, []int{401, 404}},
+	{"GET", "/health", "Upstream reachability", nil, nil, []int{503}},
+	{"GET", "/healthz", "Process liveness", nil, HealthzResponse{}, nil},
+	{"GET", "/readyz", "Whether Einthusan is reachable and still parses", nil, ReadyzResponse{}, []int{503}},
+	{"GET", "/stats", "How listing lookups were served", nil, StatsResponse{}, nil},
+}
+
+var ginParamPattern = regexp.MustCompile(`:(\w+)`)
+
+// openAPIDocument is built once, on first request.
+var openAPIDocument = sync.OnceValue(func() gin.H {
+	schemas := gin.H{"Error": gin.H{
+		"type":     "object",
+		"required": []string{"code", "message", "error"},
+		"properties": gin.H{
+			"code":            gin.H{"type": "string"},
+			"message":         gin.H{"type": "string"},
+			"error":           gin.H{"type": "string", "description": "Same as message, kept for older clients."},
+			"request_id":      gin.H{"type": "string"},
+			"upstream_status": gin.H{"type": "integer", "description": "Einthusan's HTTP status, when it answered with one we couldn't use."},
+			"retry_after":     gin.H{"type": "integer"},
+			"reason":          gin.H{"type": "string", "description": "For upstream_blocked: challenge, captcha, geo_blocked (answered 451) or access_denied."},
+		},
+	}}
+	paths := gin.H{}
+	for _, route := range apiRoutes {
+		var params []gin.H
+		for _, match := range ginParamPattern.FindAllStringSubmatch(route.path, -1) {
+			params = append(params, gin.H{"name": match[1], "in": "path", "required": true, "schema": gin.H{"type": "string"}})
+		}
+		for _, p := range route.query {
+			params = append(params, gin.H{"name": p.name, "in": "query", "required": p.required, "description": p.description, "schema": gin.H{"type": p.typ}})
+		}
+		responses := gin.H{}
+		if route.response != nil {
+			responses["200"] = gin.H{"description": "OK", "content": gin.H{"application/json": gin.H{"schema": schemaFor(reflect.TypeOf(route.response), schemas)}}}
+		} else {
+			responses["200"] = gin.H{"description": "OK"}
+		}
+		for _, status := range route.errors {
+			responses[strconv.Itoa(status)] = gin.H{"description": http.StatusText(status), "content": gin.H{"application/json": gin.H{"schema": gin.H{"$ref": "#/components/schemas/Error"}}}}
+		}
+		op := gin.H{"summary": route.summary, "responses": responses}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		path := ginParamPattern.ReplaceAllString(route.path, "{$1}")
+		item, _ := paths[path].(gin.H)
+		if item == nil {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.method)] = op
+	}
+	return gin.H{
+		"openapi":    "3.0.3",
+		"info":       gin.H{"title": "thirai api", "version": "1.0"},
+		"paths":      paths,
+		"components": gin.H{"schemas": schemas},
+	}
+})
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the JSON schema for t, registering named structs in
+// schemas and referring to them by name. Fields follow encoding/json: the
+// json tag names them, "-" hides them, and fields without omitempty are
+// listed as required.
+func schemaFor(t reflect.Type, schemas gin.H) gin.H {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return gin.H{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return gin.H{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return gin.H{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return gin.H{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case t.Kind() == reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case t.Kind() != reflect.Struct:
+		return gin.H{}
+	}
+
+	ref := gin.H{"$ref": "#/components/schemas/" + t.Name()}
+	if _, ok := schemas[t.Name()]; ok {
+		return ref
+	}
+	schemas[t.Name()] = gin.H{} // placeholder, in case the type refers to itself
+	props := gin.H{}
+	var required []string
+	addFields(t, schemas, props, &required)
+	schema := gin.H{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	schemas[t.Name()] = schema
+	return ref
+}
+
+// addFields adds t's JSON fields to props, flattening embedded structs the
+// way encoding/json does.
+func addFields(t reflect.Type, schemas, props gin.H, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			addFields(f.Type, schemas, props, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = schemaFor(f.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func serveOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPIDocument())
+}
+
+// docsPage loads Swagger UI from a CDN and points it at /openapi.json.
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>thirai api docs</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+func serveDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
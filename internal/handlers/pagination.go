@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const (
+	maxPageSize = 200
+	// maxFillPages bounds how many upstream pages one request may pull to
+	// satisfy page_size or pages.
+	maxFillPages = 5
+	// maxConcurrentPages bounds how many of those are scraped at once.
+	maxConcurrentPages = 3
+)
+
+// pageRange is the result of scraping one or more consecutive upstream pages.
+type pageRange struct {
+	Movies   []models.MovieEntry
+	LastPage int
+	HasMore  bool
+	Heading  string // heading of the first page
+	Degraded bool   // some page parsed to nothing though it listed movies
+
+	TotalResults int // upstream's match count, 0 when not shown
+	TotalPages   int // pages in the whole listing, 0 when unknown
+}
+
+// setTotals fills in the listing-wide counts from one of its pages. The
+// pager's highest page number is preferred; failing that the page count is
+// derived from the result count, and a page with no successor is the last.
+func (pr *pageRange) setTotals(page int, result scraper.Listing) {
+	pr.TotalResults = max(pr.TotalResults, result.Total)
+	switch {
+	case result.LastPage > 0:
+		pr.TotalPages = max(pr.TotalPages, result.LastPage, page)
+	case result.Total > 0:
+		pr.TotalPages = max(pr.TotalPages, (result.Total+scraper.PageSize()-1)/scraper.PageSize())
+	case !result.HasNext || len(result.Movies) < scraper.PageSize():
+		pr.TotalPages = max(pr.TotalPages, page)
+	}
+}
+
+// nextPage is the page to request after this range, or 0 when it reached
+// the end of the listing.
+func (pr pageRange) nextPage() int {
+	return nextPageAfter(pr.LastPage, pr.HasMore)
+}
+
+func nextPageAfter(page int, hasNext bool) int {
+	if !hasNext {
+		return 0
+	}
+	return page + 1
+}
+
+// parsePageSize reads the optional page_size hint, clamped to 1..maxPageSize.
+// It returns 0 when absent, and responds 400 when it isn't a number.
+func parsePageSize(c *gin.Context) (int, bool) {
+	raw := c.Query("page_size")
+	if raw == "" {
+		return 0, true
+	}
+	size, err := strconv.Atoi(raw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "page_size must be an integer")
+		return 0, false
+	}
+	return min(max(size, 1), maxPageSize), true
+}
+
+// parsePageSpan reads the optional pages= parameter: either a count of pages
+// starting at page, or an inclusive range such as 1-3, which replaces page.
+// The count is clamped to 1..maxFillPages, so a longer range is cut short and
+// next_page picks up from there. It returns a count of 0 when pages= is
+// absent, and responds 400 when it is malformed.
+func parsePageSpan(c *gin.Context, page int) (start, count int, ok bool) {
+	raw := c.Query("pages")
+	if raw == "" {
+		return page, 0, true
+	}
+	if from, to, isRange := strings.Cut(raw, "-"); isRange {
+		first, err1 := strconv.Atoi(from)
+		last, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || first < 1 || last < first {
+			respondError(c, http.StatusBadRequest, "invalid_parameter", "pages must be a count or a range like 1-3")
+			return 0, 0, false
+		}
+		return first, min(last-first+1, maxFillPages), true
+	}
+	count, err := strconv.Atoi(raw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "pages must be a count or a range like 1-3")
+		return 0, 0, false
+	}
+	return page, min(max(count, 1), maxFillPages), true
+}
+
+// pageUrl appends the upstream page parameter; page 1 is the bare URL.
+func pageUrl(base string, page int) string {
+	if page > 1 {
+		return fmt.Sprintf("%s&page=%d", base, page)
+	}
+	return base
+}
+
+// pageFetcher returns one page of a listing.
+type pageFetcher func(ctx context.Context, page int) (scraper.Listing, error)
+
+// urlPages fetches the pages of the Einthusan listing at base through the cache.
+func urlPages(language, base string) pageFetcher {
+	return func(ctx context.Context, page int) (scraper.Listing, error) {
+		return cachedScrape(ctx, language, pageUrl(base, page))
+	}
+}
+
+// fetchPages scrapes a listing starting at page and, when size is set, keeps
+// taking whole following pages until at least size movies are collected, the
+// listing runs out, or maxFillPages is reached. Pages are never split, so
+// next_page always continues exactly where this response stopped.
+func fetchPages(ctx context.Context, fetch pageFetcher, page, size int) (pageRange, error) {
+	var pr pageRange
+	for p := page; p < page+maxFillPages; p++ {
+		result, err := fetch(ctx, p)
+		if err != nil {
+			if p > page {
+				// Keep what we already have rather than failing the whole request.
+				break
+			}
+			return pageRange{}, err
+		}
+		if p == page {
+			pr.Heading = result.Heading
+		}
+		pr.Movies = append(pr.Movies, result.Movies...)
+		pr.Degraded = pr.Degraded || result.Degraded
+		pr.LastPage = p
+		pr.setTotals(p, result)
+		pr.HasMore = result.HasNext && len(result.Movies) >= scraper.PageSize()
+		if !pr.HasMore || len(pr.Movies) >= size {
+			break
+		}
+	}
+	return pr, nil
+}
+
+// fetchPageSpan scrapes count consecutive pages starting at page in parallel
+// and joins them in page order, dropping movies repeated across pages. As
+// with fetchPages, a failing later page truncates the range instead of
+// failing it, and the range stops at the first page without a successor.
+func fetchPageSpan(ctx context.Context, fetch pageFetcher, page, count int) (pageRange, error) {
+	results := make([]scraper.Listing, count)
+	errs := make([]error, count)
+	sem := make(chan struct{}, maxConcurrentPages)
+	var wg sync.WaitGroup
+	for i := range count {
+		wg.Go(func() {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			results[i], errs[i] = fetch(ctx, page+i)
+		})
+	}
+	wg.Wait()
+
+	var pr pageRange
+	for i, result := range results {
+		if errs[i] != nil {
+			if i == 0 {
+				return pageRange{}, errs[i]
+			}
+			break
+		}
+		if i == 0 {
+			pr.Heading = result.Heading
+		}
+		pr.Movies = mergeMovies(pr.Movies, result.Movies)
+		pr.Degraded = pr.Degraded || result.Degraded
+		pr.LastPage = page + i
+		pr.setTotals(page+i, result)
+		pr.HasMore = result.HasNext && len(result.Movies) >= scraper.PageSize()
+		if !pr.HasMore {
+			break
+		}
+	}
+	return pr, nil
+}
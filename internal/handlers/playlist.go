@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const (
+	defaultPlaylistSize = 20
+	maxPlaylistSize     = 50
+	// maxConcurrentPlaylist bounds the stream lookups one playlist makes at once.
+	maxConcurrentPlaylist = 4
+)
+
+// playlistEntry is one playable movie in a playlist.
+type playlistEntry struct {
+	movie  models.MovieEntry
+	stream string
+}
+
+// moviePlaylist answers /playlist/:file, where file is a language followed
+// by .m3u or .m3u8, with the first limit= movies of the category= listing
+// as an extended M3U playlist for IPTV players. Each movie's stream link is
+// resolved up front, so this needs the stream build tag; movies whose link
+// can't be resolved are left out. Each of those fetches is charged to the
+// request's scrape budget, so a long playlist waits on the limiter.
+func moviePlaylist(c *gin.Context) {
+	file := c.Param("file")
+	ext := path.Ext(file)
+	if ext != ".m3u" && ext != ".m3u8" {
+		respondError(c, http.StatusNotFound, "unsupported_playlist_format", "playlist must end in .m3u or .m3u8")
+		return
+	}
+	language, ok := checkLanguage(c, strings.TrimSuffix(file, ext))
+	if !ok {
+		return
+	}
+	source, ok := requireProvider(c, language)
+	if !ok {
+		return
+	}
+	category := strings.ToLower(c.DefaultQuery("category", "recent"))
+	if categories := source.info().Categories; !slices.Contains(categories, category) {
+		respondError(c, http.StatusBadRequest, "invalid_category", "category must be one of "+strings.Join(categories, ", "))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPlaylistSize)))
+	if err != nil || limit < 1 || limit > maxPlaylistSize {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", fmt.Sprintf("limit must be between 1 and %d", maxPlaylistSize))
+		return
+	}
+
+	pages, err := fetchPages(c.Request.Context(), func(ctx context.Context, page int) (scraper.Listing, error) {
+		return source.browse(ctx, language, category, "", page)
+	}, 1, limit)
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	movies := pages.Movies[:min(limit, len(pages.Movies))]
+
+	entries := make([]playlistEntry, len(movies))
+	errs := make([]error, len(movies))
+	sem := make(chan struct{}, maxConcurrentPlaylist)
+	var wg sync.WaitGroup
+	for i, m := range movies {
+		if m.ID == "" {
+			continue
+		}
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			streams, err := resolveStreams(c.Request.Context(), language, m.ID)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			entries[i] = playlistEntry{movie: m, stream: preferredStream(streams.Sources)}
+		})
+	}
+	wg.Wait()
+	if errors.Is(errors.Join(errs...), errStreamingDisabled) {
+		respondError(c, http.StatusNotImplemented, "streaming_disabled", errStreamingDisabled.Error())
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	group := languageName(language)
+	for _, entry := range entries {
+		if entry.stream == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "#EXTINF:-1 tvg-id=\"%s\" tvg-logo=\"%s\" group-title=\"%s\",%s\n%s\n",
+			m3uAttr(entry.movie.ID), m3uAttr(entry.movie.ImgUrl), group, m3uTitle(feedTitle(entry.movie)), entry.stream)
+	}
+	contentType := "audio/x-mpegurl; charset=utf-8"
+	if ext == ".m3u8" {
+		contentType = "application/vnd.apple.mpegurl; charset=utf-8"
+	}
+	if cacheStatus := scopeFrom(c.Request.Context()).cacheStatus(); cacheStatus != "" {
+		c.Header("X-Cache", cacheStatus)
+	}
+	c.Data(http.StatusOK, contentType, []byte(b.String()))
+}
+
+// preferredStream picks an HD MP4, then any MP4, then whatever is first.
+// Not every IPTV player handles HLS, and MP4s seek better.
+func preferredStream(sources []StreamSource) string {
+	rank := func(s StreamSource) int {
+		switch {
+		case s.Type == "mp4" && s.Quality == "hd":
+			return 2
+		case s.Type == "mp4":
+			return 1
+		}
+		return 0
+	}
+	if len(sources) == 0 {
+		return ""
+	}
+	// MaxFunc returns the first of equally ranked sources.
+	return slices.MaxFunc(sources, func(a, b StreamSource) int { return rank(a) - rank(b) }).URL
+}
+
+// m3uAttr makes s safe inside a quoted #EXTINF attribute.
+func m3uAttr(s string) string {
+	return strings.NewReplacer(`"`, "'", "\n", " ", "\r", " ").Replace(s)
+}
+
+// m3uTitle keeps a display title on its #EXTINF line.
+func m3uTitle(s string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(s)
+}
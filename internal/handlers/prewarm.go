@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"apppp/internal/models"
+)
+
+const defaultPrewarmPages = 3
+
+// StartPrewarm keeps the first pages of the popular and recent listings of
+// PREWARM_LANGUAGES (a comma-separated list, or "all") in the cache, so
+// browse and trending requests for them rarely wait on Einthusan. It is a
+// no-op when the list is empty.
+//
+// Every PREWARM_INTERVAL_SECONDS the listings are re-scraped one page at a
+// time, up to PREWARM_PAGES pages each, and stored whether or not the cached
+// copy has expired yet. The default interval is four fifths of the browse
+// cache TTL, so entries are replaced shortly before they would go stale.
+// Each round's recent listings also feed the /events new-release streams.
+func StartPrewarm(raw string) {
+	languages := prewarmLanguages(raw)
+	if len(languages) == 0 {
+		return
+	}
+	watchedLanguages = languages
+	pages := min(max(EnvInt("PREWARM_PAGES", defaultPrewarmPages), 1), maxFillPages)
+	defaultInterval := cache.ttlFor(browseUrlFor(languages[0], "recent")) * 4 / 5
+	interval := time.Duration(EnvInt("PREWARM_INTERVAL_SECONDS", int(defaultInterval/time.Second))) * time.Second
+	if interval <= 0 {
+		log.Printf("config: ignoring PREWARM_INTERVAL_SECONDS, must be positive")
+		interval = max(defaultInterval, time.Minute)
+	}
+	log.Printf("prewarm: refreshing %d pages of %s every %s", pages, strings.Join(languages, ", "), interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if prewarmRunning.CompareAndSwap(false, true) {
+				prewarm(context.Background(), languages, pages)
+				prewarmRunning.Store(false)
+			} else {
+				log.Printf("prewarm: skipping round, one is already running")
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// prewarmRunning is set while a round runs, so a round started from
+// /admin/prewarm and a scheduled one never overlap.
+var prewarmRunning atomic.Bool
+
+// prewarmLanguages parses PREWARM_LANGUAGES, dropping unsupported entries.
+func prewarmLanguages(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if strings.EqualFold(raw, "all") {
+		return supportedLanguages
+	}
+	var languages []string
+	for _, part := range strings.Split(raw, ",") {
+		language := strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case language == "" || slices.Contains(languages, language):
+		case !slices.Contains(supportedLanguages, language):
+			log.Printf("config: ignoring PREWARM_LANGUAGES entry %q, not a supported language", part)
+		default:
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// browseUrlFor is browseUrl for a category known to exist.
+func browseUrlFor(language, category string) string {
+	base, _ := browseUrl(language, category)
+	return base
+}
+
+// prewarm runs one refresh round. Pages are scraped sequentially to keep the
+// load on Einthusan even, and a round is abandoned when Einthusan asks us to
+// back off.
+func prewarm(ctx context.Context, languages []string, pages int) {
+	start := time.Now()
+	refreshed := 0
+	for _, language := range languages {
+		for _, category := range []string{"popular", "recent"} {
+			base := browseUrlFor(language, category)
+			var movies []models.MovieEntry
+			for page := 1; page <= pages; page++ {
+				url := pageUrl(base, page)
+				result, err := upstream.ScrapeListing(ctx, url)
+				var backoff *backoffError
+				if errors.As(err, &backoff) {
+					log.Printf("prewarm: stopping early, %v", err)
+					return
+				}
+				if err != nil {
+					log.Printf("prewarm: %s failed: %v", url, err)
+					break
+				}
+				cache.set(language, url, result)
+				localIndex.add(language, result.Movies)
+				movies = append(movies, result.Movies...)
+				refreshed++
+				if !result.HasNext {
+					break
+				}
+			}
+			if category == "recent" && len(movies) > 0 {
+				releases.observe(language, movies)
+			}
+		}
+	}
+	log.Printf("prewarm: refreshed %d pages in %s", refreshed, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+// projectionKey is where projectFields leaves the requested field names.
+const projectionKey = "fields"
+
+// projectableTypes are the list items fields= can trim.
+var projectableTypes = []reflect.Type{reflect.TypeFor[models.MovieEntry](), reflect.TypeFor[IndexedMovie](), reflect.TypeFor[CombinedMovie]()}
+
+// projectableFields are the JSON names of every projectable type's fields.
+var projectableFields = sync.OnceValue(func() []string {
+	var names []string
+	for _, t := range projectableTypes {
+		for _, name := range jsonFieldNames(t) {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+})
+
+// jsonFieldNames lists the JSON names of struct t's fields, including those
+// of embedded structs, in declaration order.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		names = append(names, cmp.Or(name, f.Name))
+	}
+	return names
+}
+
+// projectFields lets every list endpoint answer fields=id,title with only
+// those fields of each movie, to cut payloads for mobile clients. basic and
+// full keep their meaning and are left to the handlers. A list naming an
+// unknown field is answered 400. Handlers see a list as fields=full, so
+// projecting never loses a field the list asks for.
+func projectFields() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.Query("fields")
+		if raw == "" || raw == "basic" || raw == "full" {
+			c.Next()
+			return
+		}
+		var fields []string
+		for _, part := range strings.Split(raw, ",") {
+			field := strings.TrimSpace(part)
+			if field == "" {
+				continue
+			}
+			if !slices.Contains(projectableFields(), field) {
+				body := errorBody("invalid_parameter", "fields must be basic, full or a comma-separated list of movie fields; unknown field "+field)
+				body["supported"] = projectableFields()
+				abortWithError(c, http.StatusBadRequest, body)
+				return
+			}
+			fields = append(fields, field)
+		}
+		c.Set(projectionKey, fields)
+		c.Next()
+	}
+}
+
+// projection returns the fields the request asked for, or nil to keep them all.
+func projection(c *gin.Context) []string {
+	fields, _ := c.Value(projectionKey).([]string)
+	return fields
+}
+
+// projectResponse trims every movie list in obj down to fields, keeping the
+// rest of obj as it is. The lists are found from obj's Go type: its fields
+// holding a slice of a projectable type. Anything else is returned as is.
+func projectResponse(obj any, fields []string) any {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return obj
+	}
+	var lists []string
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Type.Kind() == reflect.Slice && slices.Contains(projectableTypes, f.Type.Elem()) {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			lists = append(lists, cmp.Or(name, f.Name))
+		}
+	}
+	if len(lists) == 0 {
+		return obj
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return obj
+	}
+	for _, list := range lists {
+		items, _ := doc[list].([]any)
+		for i, item := range items {
+			movie, _ := item.(map[string]any)
+			kept := make(map[string]any, len(fields))
+			for _, field := range fields {
+				if v, ok := movie[field]; ok {
+					kept[field] = v
+				}
+			}
+			items[i] = kept
+		}
+	}
+	return plainNumbers(doc)
+}
+
+// plainNumbers turns the json.Numbers of a decoded document back into
+// int64 or float64, so MessagePack encodes them as numbers, not strings.
+func plainNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = plainNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = plainNumbers(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// provider is a source site the API can read movies from. Listing methods
+// return one page of results; paging, page_size filling and the response
+// shapes are shared, so a new site only has to implement these.
+type provider interface {
+	info() ProviderInfo
+	search(ctx context.Context, language, query string, page int) (scraper.Listing, error)
+	browse(ctx context.Context, language, category, window string, page int) (scraper.Listing, error)
+	byActor(ctx context.Context, language, actorID string, page int) (scraper.Listing, error)
+	details(ctx context.Context, language, id string) (*models.MovieDetail, error)
+}
+
+// ProviderInfo describes a provider on /providers.
+type ProviderInfo struct {
+	Name       string   `json:"name"`
+	BaseUrl    string   `json:"base_url"`
+	Languages  []string `json:"languages"`
+	Categories []string `json:"categories"`        // Accepted by browse's category=
+	Windows    []string `json:"windows,omitempty"` // Accepted by browse's window=, for category=popular
+	Default    bool     `json:"default"`
+}
+
+// ProvidersResponse is the /providers registry.
+type ProvidersResponse struct {
+	Providers []ProviderInfo `json:"providers"`
+}
+
+// defaultProvider answers requests that don't pass provider=.
+const defaultProvider = "einthusan"
+
+// providers is every registered source, keyed by the name clients pass in
+// provider=.
+var providers = map[string]provider{
+	defaultProvider: einthusanProvider{},
+}
+
+// providerNames lists the registered providers in a stable order.
+func providerNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// requireProvider reads the optional provider= query parameter, defaulting
+// to Einthusan. It responds 400 listing the registered providers when the
+// name is unknown, or when the provider doesn't serve language.
+func requireProvider(c *gin.Context, language string) (provider, bool) {
+	p, ok := lookupProvider(c)
+	if !ok || !providerServes(c, p, language) {
+		return nil, false
+	}
+	return p, true
+}
+
+// lookupProvider reads provider= alone, for routes that check their
+// languages against it one at a time.
+func lookupProvider(c *gin.Context) (provider, bool) {
+	name := strings.ToLower(strings.TrimSpace(c.DefaultQuery("provider", defaultProvider)))
+	p, ok := providers[name]
+	if !ok {
+		body := errorBody("unsupported_provider", "unsupported provider")
+		body["supported"] = providerNames()
+		abortWithError(c, http.StatusBadRequest, body)
+		return nil, false
+	}
+	return p, true
+}
+
+// providerServes responds 400 when p doesn't serve language.
+func providerServes(c *gin.Context, p provider, language string) bool {
+	if info := p.info(); !slices.Contains(info.Languages, language) {
+		body := errorBody("unsupported_language", fmt.Sprintf("%s does not serve %s", info.Name, language))
+		body["supported"] = info.Languages
+		abortWithError(c, http.StatusBadRequest, body)
+		return false
+	}
+	return true
+}
+
+// einthusanOnly guards routes built from Einthusan's own URLs, such as the
+// genre and year listings, which the provider interface has no method for.
+// provider= may name Einthusan or be left out; any other name is a 400
+// rather than being silently ignored.
+func einthusanOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := strings.ToLower(strings.TrimSpace(c.DefaultQuery("provider", defaultProvider)))
+		if name != defaultProvider {
+			body := errorBody("unsupported_provider", "only einthusan serves this route")
+			body["supported"] = []string{defaultProvider}
+			abortWithError(c, http.StatusBadRequest, body)
+			return
+		}
+		c.Next()
+	}
+}
+
+func listProviders(c *gin.Context) {
+	resp := ProvidersResponse{Providers: []ProviderInfo{}}
+	for _, name := range providerNames() {
+		resp.Providers = append(resp.Providers, providers[name].info())
+	}
+	respond(c, http.StatusOK, resp)
+}
+
+// einthusanProvider serves Einthusan through the shared cache, with
+// upstream doing the fetching and parsing.
+type einthusanProvider struct{}
+
+func (einthusanProvider) info() ProviderInfo {
+	return ProviderInfo{
+		Name:       defaultProvider,
+		BaseUrl:    scraper.Einthusan.BaseURL(),
+		Languages:  knownLanguages(),
+		Categories: []string{"recent", "popular"},
+		Windows:    popularWindowNames,
+		Default:    true,
+	}
+}
+
+func (einthusanProvider) search(ctx context.Context, language, query string, page int) (scraper.Listing, error) {
+	return searchListing(ctx, language, query, page)
+}
+
+func (einthusanProvider) browse(ctx context.Context, language, category, window string, page int) (scraper.Listing, error) {
+	base, ok := browseUrl(language, category)
+	if !ok {
+		return scraper.Listing{}, fmt.Errorf("unknown category %q", category)
+	}
+	if category == "popular" && window != "" {
+		base = popularUrl(language, window)
+	}
+	return cachedScrape(ctx, language, pageUrl(base, page))
+}
+
+func (einthusanProvider) byActor(ctx context.Context, language, actorID string, page int) (scraper.Listing, error) {
+	return cachedScrape(ctx, language, pageUrl(actorUrl(language, actorID), page))
+}
+
+func (einthusanProvider) details(ctx context.Context, language, id string) (*models.MovieDetail, error) {
+	return upstream.ScrapeMovieDetail(ctx, language, id)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// stubProvider serves Tamil only, recording which methods were called.
+type stubProvider struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (sp *stubProvider) record(call string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.calls = append(sp.calls, call)
+}
+
+func (sp *stubProvider) info() ProviderInfo {
+	return ProviderInfo{Name: "stub", Languages: []string{"tamil"}, Categories: []string{"recent", "popular"}}
+}
+
+func (sp *stubProvider) search(ctx context.Context, language, query string, page int) (scraper.Listing, error) {
+	sp.record("search")
+	return scraper.Listing{Movies: []models.MovieEntry{{ID: "s1", Title: query}}}, nil
+}
+
+func (sp *stubProvider) browse(ctx context.Context, language, category, window string, page int) (scraper.Listing, error) {
+	sp.record("browse " + category)
+	return scraper.Listing{Movies: []models.MovieEntry{{ID: category, Title: category}}}, nil
+}
+
+func (sp *stubProvider) byActor(ctx context.Context, language, actorID string, page int) (scraper.Listing, error) {
+	sp.record("byActor")
+	return scraper.Listing{}, nil
+}
+
+func (sp *stubProvider) details(ctx context.Context, language, id string) (*models.MovieDetail, error) {
+	sp.record("details")
+	return &models.MovieDetail{ID: id, Language: language, Title: "Stubbed"}, nil
+}
+
+// useStubProvider registers a stubProvider as provider=stub for the rest of the test.
+func useStubProvider(t *testing.T) *stubProvider {
+	t.Helper()
+	sp := &stubProvider{}
+	providers["stub"] = sp
+	cache.flush("", "")
+	t.Cleanup(func() {
+		delete(providers, "stub")
+		cache.flush("", "")
+	})
+	return sp
+}
+
+func TestProviderRouting(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		wantCode  int
+		wantCalls int
+	}{
+		{"trending", http.MethodGet, "/trending/tamil?provider=stub", "", http.StatusOK, 2},
+		{"multi-search", http.MethodGet, "/search?q=theri&languages=tamil&provider=stub", "", http.StatusOK, 1},
+		{"multi-search in an unserved language", http.MethodGet, "/search?q=theri&languages=hindi&provider=stub", "", http.StatusBadRequest, 0},
+		{"batch", http.MethodPost, "/movies/batch?language=tamil&provider=stub", `["a1", "a2"]`, http.StatusOK, 2},
+		{"graphql", http.MethodPost, "/graphql", `{"query": "{ movie(provider: \"stub\", language: \"tamil\", id: \"a1\") { title } }"}`, http.StatusOK, 1},
+		{"genre", http.MethodGet, "/genre/tamil/action?provider=stub", "", http.StatusBadRequest, 0},
+		{"year", http.MethodGet, "/year/tamil/2016?provider=stub", "", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := useStubProvider(t)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			NewRouter().ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.wantCode, w.Body)
+			}
+			if len(sp.calls) != tt.wantCalls {
+				t.Errorf("provider calls = %v, want %d", sp.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGraphQLDetailsUseListingProvider(t *testing.T) {
+	sp := useStubProvider(t)
+	w := httptest.NewRecorder()
+	body := `{"query": "{ browse(provider: \"stub\", language: \"tamil\") { movies { details { title } } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	NewRouter().ServeHTTP(w, req)
+
+	var result struct {
+		Data struct {
+			Browse struct {
+				Movies []struct {
+					Details struct {
+						Title string `json:"title"`
+					} `json:"details"`
+				} `json:"movies"`
+			} `json:"browse"`
+		} `json:"data"`
+		Errors []any `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 || len(result.Data.Browse.Movies) != 1 || result.Data.Browse.Movies[0].Details.Title != "Stubbed" {
+		t.Errorf("body %s, provider calls %v", w.Body, sp.calls)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultScrapeRate  = 5
+	defaultScrapeBurst = 10
+
+	defaultClientRatePerMinute = 60
+	defaultClientBurst         = 20
+	// clientIdleTTL is how long an unused per-client bucket is kept; by then
+	// it has refilled, so dropping it loses nothing.
+	clientIdleTTL = 10 * time.Minute
+)
+
+// scrapeLimiter is one token bucket shared by every scraping route, so a burst
+// of traffic can't turn into a burst of upstream requests and get our IP
+// banned. A request pays one token to get in and one more for each further
+// upstream fetch it makes (see chargeFetch). SCRAPE_RATE_LIMIT is requests per second (0 or less disables the
+// limit) and SCRAPE_RATE_BURST is the bucket size.
+var scrapeLimiter = newScrapeLimiter(envFloat("SCRAPE_RATE_LIMIT", defaultScrapeRate), EnvInt("SCRAPE_RATE_BURST", defaultScrapeBurst))
+
+func newScrapeLimiter(perSecond float64, burst int) *rate.Limiter {
+	if perSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), max(burst, 1))
+}
+
+// tooManyRequests answers 429 with how long to wait before retrying.
+func tooManyRequests(c *gin.Context, delay time.Duration) {
+	wait := int(math.Ceil(delay.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(wait))
+	body := errorBody("rate_limited", "too many requests, retry later")
+	body["retry_after"] = wait
+	abortWithError(c, http.StatusTooManyRequests, body)
+}
+
+// limitScrapes answers 429 with Retry-After once scrapeLimiter is out of
+// tokens. The token is only spent when the request goes through.
+func limitScrapes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reservation := scrapeLimiter.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			tooManyRequests(c, delay)
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientLimiter gives every client IP its own token bucket, so one busy
+// client is turned away before it can drain the shared scrapeLimiter for
+// everyone else. CLIENT_RATE_LIMIT is requests per minute (0 or less
+// disables it), CLIENT_RATE_BURST the bucket size, and RATE_LIMIT_ALLOWLIST a
+// comma-separated list of IPs or CIDR ranges that are never limited.
+type clientLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*clientBucket
+	limit     rate.Limit
+	burst     int
+	allowlist []netip.Prefix
+	lastSweep time.Time
+}
+
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+var clientLimits = newClientLimiter(
+	envFloat("CLIENT_RATE_LIMIT", defaultClientRatePerMinute),
+	EnvInt("CLIENT_RATE_BURST", defaultClientBurst),
+	Setting("RATE_LIMIT_ALLOWLIST"),
+)
+
+func newClientLimiter(perMinute float64, burst int, allowlist string) *clientLimiter {
+	cl := &clientLimiter{buckets: make(map[string]*clientBucket), limit: rate.Inf, burst: max(burst, 1)}
+	if perMinute > 0 {
+		cl.limit = rate.Limit(perMinute / 60)
+	}
+	for _, part := range strings.Split(allowlist, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(part)
+			if addrErr != nil {
+				log.Printf("config: ignoring invalid RATE_LIMIT_ALLOWLIST entry %q", part)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		cl.allowlist = append(cl.allowlist, prefix.Masked())
+	}
+	return cl
+}
+
+func (cl *clientLimiter) allowlisted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range cl.allowlist {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// reserve takes a token from ip's bucket, returning how long the client must
+// wait when there is none. Idle buckets are swept at most once per
+// clientIdleTTL, so the map stays bounded by recently active clients.
+func (cl *clientLimiter) reserve(ip string) time.Duration {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	now := time.Now()
+	if now.Sub(cl.lastSweep) > clientIdleTTL {
+		for key, b := range cl.buckets {
+			if now.Sub(b.lastSeen) > clientIdleTTL {
+				delete(cl.buckets, key)
+			}
+		}
+		cl.lastSweep = now
+	}
+	b, ok := cl.buckets[ip]
+	if !ok {
+		b = &clientBucket{limiter: rate.NewLimiter(cl.limit, cl.burst)}
+		cl.buckets[ip] = b
+	}
+	b.lastSeen = now
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// limitClients answers 429 with Retry-After once the caller's own bucket is
+// empty. Callers are told apart by c.ClientIP().
+func limitClients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if clientLimits.limit == rate.Inf || clientLimits.allowlisted(ip) {
+			c.Next()
+			return
+		}
+		if delay := clientLimits.reserve(ip); delay > 0 {
+			tooManyRequests(c, delay)
+			return
+		}
+		c.Next()
+	}
+}
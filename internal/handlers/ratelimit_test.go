@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Fan-out routes pay a token per upstream fetch, not one per request.
+func TestFanOutChargesEveryFetch(t *testing.T) {
+	tests := []struct {
+		name, target, body string
+	}{
+		{"batch", "/movies/batch?language=tamil", `["a1", "a2", "a3", "a4", "a5"]`},
+		{"graphql", "/graphql", `{"query": "{ a: movie(language: \"tamil\", id: \"a1\") { title } b: movie(language: \"tamil\", id: \"a2\") { title } c: movie(language: \"tamil\", id: \"a3\") { title } d: movie(language: \"tamil\", id: \"a4\") { title } e: movie(language: \"tamil\", id: \"a5\") { title } }"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := fakeUpstream(t, http.NotFound)
+			// Three tokens that never refill: one lets the request in, and
+			// with the first fetch free, two more fetches can be paid for.
+			previous := scrapeLimiter
+			scrapeLimiter = rate.NewLimiter(rate.Every(time.Hour), 3)
+			t.Cleanup(func() { scrapeLimiter = previous })
+
+			ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+			defer cancel()
+			req := httptest.NewRequestWithContext(ctx, http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			NewRouter().ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d; body %s", w.Code, w.Body)
+			}
+			if n := hits.Load(); n != 3 {
+				t.Errorf("upstream hit %d times, want 3", n)
+			}
+			if !strings.Contains(w.Body.String(), "too many upstream requests queued") {
+				t.Errorf("unpaid fetches weren't reported as busy: %s", w.Body)
+			}
+			if tt.name == "batch" {
+				var resp BatchResponse
+				json.Unmarshal(w.Body.Bytes(), &resp)
+				if resp.Failed != 5 {
+					t.Errorf("failed = %d, want all 5 (3 missing, 2 turned away)", resp.Failed)
+				}
+			}
+		})
+	}
+}
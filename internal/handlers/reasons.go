@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"apppp/internal/models"
+)
+
+// Reasons reported alongside an empty movie list, so clients can tell
+// "nothing matched" apart from "everything was filtered away".
+const (
+	reasonNoMatches     = "no_matches"      // the upstream search found nothing
+	reasonUpstreamEmpty = "upstream_empty"  // the upstream listing has no entries
+	reasonFilteredOut   = "filtered_out"    // results existed but our filters removed them all
+	reasonDegraded      = "scrape_degraded" // the upstream listed movies our selectors couldn't read
+)
+
+// emptyReason returns reason when movies is empty and "" otherwise.
+func emptyReason(movies []models.MovieEntry, reason string) string {
+	if len(movies) == 0 {
+		return reason
+	}
+	return ""
+}
+
+// listingReason is emptyReason for a scraped listing, blaming the selectors
+// rather than the upstream when the scrape was degraded.
+func listingReason(movies []models.MovieEntry, degraded bool, reason string) string {
+	if degraded {
+		reason = reasonDegraded
+	}
+	return emptyReason(movies, reason)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+)
+
+// msgpackHandle encodes canonically, sorting map keys, so identical data always
+// produces identical bytes. encoding/json already sorts map keys for JSON.
+var msgpackHandle = &codec.MsgpackHandle{}
+
+func init() {
+	msgpackHandle.Canonical = true
+}
+
+// msgpackRender is gin's MsgPack render with the canonical handle above.
+type msgpackRender struct {
+	data any
+}
+
+func (r msgpackRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return codec.NewEncoder(w, msgpackHandle).Encode(r.data)
+}
+
+func (r msgpackRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/msgpack; charset=utf-8")
+}
+
+// respond writes obj as MessagePack when the client asks for it in Accept,
+// and as JSON otherwise. MessagePack reuses the structs' json field names.
+// Responses built from scraped listings also get an X-Cache header, and
+// their movie lists are trimmed to any fields= list (see projectFields).
+func respond(c *gin.Context, status int, obj any) {
+	c.Header("Vary", "Accept")
+	if fields := projection(c); fields != nil {
+		obj = projectResponse(obj, fields)
+	}
+	if cacheStatus := scopeFrom(c.Request.Context()).cacheStatus(); cacheStatus != "" {
+		c.Header("X-Cache", cacheStatus)
+	}
+	if wantsMsgPack(c.GetHeader("Accept")) {
+		c.Render(status, msgpackRender{data: obj})
+		return
+	}
+	c.JSON(status, obj)
+}
+
+func wantsMsgPack(accept string) bool {
+	return strings.Contains(accept, "application/msgpack") || strings.Contains(accept, "application/x-msgpack")
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+func TestRespondMsgPackRoundTrip(t *testing.T) {
+	want := models.BrowseResponse{
+		Category: "recent", HasMore: true, Language: "tamil", NextPage: 2, Page: 1, PageSize: scraper.PageSize(), Count: 2, TotalPages: 62,
+		Movies: []models.MovieEntry{
+			{ID: "A00x", Title: "Theri", Year: 2016, ImgUrl: "https://img.einthusan.io/tamil/A00x.jpg", Views: 1000},
+			{ID: "B01x", Title: "Kaththi", Duration: "2h 46m"},
+		},
+	}
+	r := gin.New()
+	r.Use(withRequestScope())
+	r.GET("/browse", func(c *gin.Context) { respond(c, http.StatusOK, want) })
+
+	req := httptest.NewRequest(http.MethodGet, "/browse", nil)
+	req.Header.Set("Accept", "application/msgpack")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/msgpack") {
+		t.Fatalf("Content-Type = %q, want application/msgpack", ct)
+	}
+
+	raw := w.Body.Bytes()
+	var got models.BrowseResponse
+	if err := codec.NewDecoderBytes(raw, msgpackHandle).Decode(&got); err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+
+	// Other clients see the json field names.
+	var fields map[string]any
+	if err := codec.NewDecoderBytes(raw, msgpackHandle).Decode(&fields); err != nil {
+		t.Fatalf("decoding as a map: %v", err)
+	}
+	for _, name := range []string{"has_more", "next_page", "movies"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("field %q missing; got keys of %v", name, fields)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package handlers serves the API: the routes, their middleware, and the
+// fetching, caching and rate limiting behind them. It reaches Einthusan
+// only through upstream, a scraper.Scraper.
+package handlers
+
+import (
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter builds the engine with every middleware and route registered.
+func NewRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(requestLogger(), instrumentRequests(), gin.Recovery())
+
+	r.Use(cors.New(corsConfig(Setting("CORS_ALLOWED_ORIGINS"), Setting("CORS_ALLOWED_METHODS"), Setting("CORS_ALLOWED_HEADERS"))))
+	r.Use(compressResponses("/export", "/events/:language"))
+	r.Use(conditionalResponses(loadMaxAges(Setting("CACHE_MAX_AGE")), "/export", "/events/:language"))
+	r.Use(withRequestScope())
+	r.Use(projectFields())
+	if headerOverrides {
+		r.Use(withHeaderOverrides())
+	}
+
+	r.GET("/", index)
+	r.GET("/openapi.json", serveOpenAPI)
+	r.GET("/docs", serveDocs)
+
+	// Every route that may reach Einthusan shares one rate limit, after each
+	// client's own. With API keys configured, these and the watchlist also
+	// need a key with quota left.
+	scrapes := r.Group("", requireAPIKey(), limitClients(), limitScrapes())
+
+	// Single-segment routes never match an empty :language, so answer them explicitly.
+	for _, path := range []string{"/search/", "/genre/", "/filters/", "/changes/", "/trending/", "/events/", "/v2/search/"} {
+		r.GET(path, missingLanguage)
+	}
+
+	// 1. SEARCH WITH PAGINATION
+	scrapes.GET("/search/:language", searchMovies)
+
+	// 1a. SEARCH THE LOCAL INDEX (live search when it has nothing)
+	scrapes.GET("/index/search", searchIndex)
+
+	// 1b. SEARCH ACROSS SEVERAL LANGUAGES
+	scrapes.GET("/search", multiSearch)
+
+	// 2. BROWSE (falls back to DEFAULT_LANGUAGE when the language is omitted)
+	scrapes.GET("/language/:language", browse)
+	scrapes.GET("/language/", browse)
+
+	// 2b. TRENDING (popular and recent in one call)
+	scrapes.GET("/trending/:language", trending)
+
+	// 3. ACTORS
+	scrapes.GET("/actors/:language/:actorcode", actorFilmography)
+
+	// 4. GENRE
+	scrapes.GET("/genre/:language", einthusanOnly(), browseByRating)
+
+	// 4b. GENRE BY NAME
+	scrapes.GET("/genre/:language/:genre", einthusanOnly(), browseGenre)
+
+	// 5. DECADE
+	scrapes.GET("/decade/:language/:decade", einthusanOnly(), browseDecade)
+
+	// 6. YEAR
+	scrapes.GET("/year/:language/:year", einthusanOnly(), browseYear)
+
+	// 7. WATCH
+	scrapes.GET("/watch", watchMovie)
+
+	// 8. AVAILABILITY
+	scrapes.GET("/available/:language/:id", movieAvailability)
+
+	// 9. FILTERS
+	scrapes.GET("/filters/:language", listFilters)
+
+	// 10. CHANGES SINCE THE LAST CATALOG SNAPSHOT
+	scrapes.GET("/changes/:language", listChanges)
+
+	// 11. IMAGE PROXY
+	scrapes.GET("/img", proxyImage)
+
+	// 12. FULL CATALOG EXPORT (streamed NDJSON)
+	scrapes.GET("/export", exportCatalog)
+
+	// 13. MOVIE DETAIL
+	scrapes.GET("/movie/:language/:id", showMovie)
+
+	// 13a. SIMILAR MOVIES
+	scrapes.GET("/similar/:language/:movieid", similarMovies)
+
+	// 13b. MOVIE DETAILS IN BULK
+	scrapes.POST("/movies/batch", batchMovies)
+
+	// 13c. SUBTITLES (proxied, optionally converted between SRT and VTT)
+	scrapes.GET("/subtitles/:language/:movieid", proxySubtitles)
+
+	// 14. STREAM LINKS (needs the stream build tag)
+	scrapes.GET("/stream/:language/:movieid", streamLinks)
+
+	// 15. EXTERNAL METADATA MATCH
+	scrapes.GET("/match/:language/:movieid", matchMovie)
+
+	// 16. NEW RELEASES FEED (/feed/tamil.rss or /feed/tamil.atom)
+	scrapes.GET("/feed/:file", releaseFeed)
+
+	// 17. STREMIO ADDON (install by the /manifest.json URL)
+	r.GET("/manifest.json", stremioManifestHandler)
+	scrapes.GET("/catalog/movie/:id", stremioCatalogHandler)
+	scrapes.GET("/catalog/movie/:id/:extra", stremioCatalogHandler)
+	scrapes.GET("/meta/movie/:id", stremioMetaHandler)
+	scrapes.GET("/stream/movie/:id", stremioStreamHandler)
+
+	// 17b. IPTV PLAYLIST (/playlist/tamil.m3u; needs the stream build tag)
+	scrapes.GET("/playlist/:file", moviePlaylist)
+
+	// 18. GRAPHQL
+	scrapes.POST("/graphql", serveGraphQL)
+
+	// 19. LANGUAGES (read from Einthusan's language picker)
+	scrapes.GET("/languages", listLanguages)
+
+	// 20. V2 LISTINGS (one ListPage envelope, paged by next_cursor)
+	v2 := scrapes.Group("/v2")
+	v2.GET("/search/:language", searchV2)
+	v2.GET("/language/:language", browseV2)
+	v2.GET("/language/", browseV2)
+	v2.GET("/actors/:language/:actorcode", actorV2)
+
+	// New-release notifications for PREWARM_LANGUAGES (Server-Sent Events)
+	r.GET("/events/:language", requireAPIKey(), releaseEvents)
+
+	r.GET("/usage", requireAPIKey(), showUsage)
+	r.GET("/providers", listProviders)
+
+	watchlist := r.Group("/watchlist", requireAPIKey(), requireWatchlist())
+	watchlist.POST("", addToWatchlist)
+	watchlist.GET("", listWatchlist)
+	watchlist.DELETE("/:id", removeFromWatchlist)
+
+	downloads := r.Group("/downloads", requireAPIKey(), requireDownloads())
+	downloads.POST("", queueDownload)
+	downloads.GET("", listDownloads)
+	downloads.GET("/:id", showDownload)
+	downloads.DELETE("/:id", cancelDownload)
+
+	admin := r.Group("/admin", requireAdmin())
+	admin.GET("/cache", listCache)
+	admin.DELETE("/cache", evictCacheEntry)
+	admin.POST("/cache/flush", flushCache)
+	admin.GET("/breaker", showBreaker)
+	admin.GET("/errors", listErrors)
+	admin.POST("/prewarm", triggerPrewarm)
+	admin.GET("/config", showConfig)
+	r.GET("/config", requireAdmin(), showConfig)
+
+	// Debug routes are only registered when DEBUG is set.
+	if envBool("DEBUG", false) {
+		r.GET("/debug/errors", debugErrors)
+	}
+
+	r.GET("/health", health)
+	r.GET("/healthz", healthz)
+	r.GET("/readyz", readyz)
+	r.GET("/stats", showStats)
+	r.GET("/metrics", showMetrics)
+
+	return r
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultUpstreamConcurrency    = 4
+	defaultUpstreamMinDelayMs     = 200
+	defaultUpstreamJitterMs       = 100
+	defaultUpstreamQueueTimeoutMs = 10000
+)
+
+// errUpstreamBusy is returned when a request waited longer than
+// UPSTREAM_QUEUE_TIMEOUT_MS for its turn to go upstream.
+var errUpstreamBusy = errors.New("too many upstream requests queued")
+
+// upstreamScheduler keeps us polite to Einthusan however busy the API gets:
+// at most UPSTREAM_CONCURRENCY requests are in flight at once (0 for no
+// cap), and each one starts at least UPSTREAM_MIN_DELAY_MS after the one
+// before, plus up to UPSTREAM_JITTER_MS at random so the traffic doesn't tick
+// like a bot. Requests beyond that queue for up to UPSTREAM_QUEUE_TIMEOUT_MS.
+//
+// scrapeLimiter turns clients away once they outrun it; this instead holds
+// the fetches that do get through, including retries, mirror fallbacks and
+// the refresher's, so that a burst of them is spread out upstream.
+type upstreamScheduler struct {
+	slots        chan struct{} // nil when concurrency is uncapped
+	minDelay     time.Duration
+	jitter       time.Duration
+	queueTimeout time.Duration
+
+	mu   sync.Mutex
+	next time.Time // earliest start for the next request
+
+	queued, inFlight atomic.Int64
+}
+
+var scheduler = newUpstreamScheduler(
+	EnvInt("UPSTREAM_CONCURRENCY", defaultUpstreamConcurrency),
+	time.Duration(EnvInt("UPSTREAM_MIN_DELAY_MS", defaultUpstreamMinDelayMs))*time.Millisecond,
+	time.Duration(EnvInt("UPSTREAM_JITTER_MS", defaultUpstreamJitterMs))*time.Millisecond,
+	time.Duration(EnvInt("UPSTREAM_QUEUE_TIMEOUT_MS", defaultUpstreamQueueTimeoutMs))*time.Millisecond,
+)
+
+func newUpstreamScheduler(concurrency int, minDelay, jitter, queueTimeout time.Duration) *upstreamScheduler {
+	s := &upstreamScheduler{minDelay: max(minDelay, 0), jitter: max(jitter, 0), queueTimeout: queueTimeout}
+	if concurrency > 0 {
+		s.slots = make(chan struct{}, concurrency)
+	}
+	return s
+}
+
+// acquire waits for a free slot and then for the request's turn, returning
+// a func that frees the slot. It gives up with errUpstreamBusy after the
+// queue timeout, or with ctx's error if ctx ends first.
+func (s *upstreamScheduler) acquire(ctx context.Context) (func(), error) {
+	s.queued.Add(1)
+	defer s.queued.Add(-1)
+	var deadline <-chan time.Time
+	if s.queueTimeout > 0 {
+		timer := time.NewTimer(s.queueTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	release := func() {}
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+			release = func() { <-s.slots }
+		case <-deadline:
+			return nil, errUpstreamBusy
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// Claim the next start time now, so requests that queue together leave
+	// in order, each a delay apart, rather than all waking at once.
+	s.mu.Lock()
+	now := time.Now()
+	start := now
+	if s.next.After(start) {
+		start = s.next
+	}
+	gap := s.minDelay
+	if s.jitter > 0 {
+		gap += time.Duration(rand.Int64N(int64(s.jitter) + 1))
+	}
+	s.next = start.Add(gap)
+	s.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-deadline:
+			release()
+			return nil, errUpstreamBusy
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	s.inFlight.Add(1)
+	return func() {
+		s.inFlight.Add(-1)
+		release()
+	}, nil
+}
+
+// do sends req through client once the scheduler lets it. The slot is held
+// until the response body is closed, since reading it still loads Einthusan.
+func (s *upstreamScheduler) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	release, err := s.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &releasingBody{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+// releasingBody frees a scheduler slot when the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
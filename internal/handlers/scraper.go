@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// checkBlocked is scraper.DetectBlock, also counting the block in
+// upstream_blocked_total.
+func checkBlocked(res *http.Response, doc *goquery.Document) error {
+	err := scraper.DetectBlock(res, doc)
+	observeBlock(err)
+	return err
+}
+
+// observeBlock counts err in upstream_blocked_total if it is a block page.
+func observeBlock(err error) {
+	var blocked *scraper.BlockedError
+	if errors.As(err, &blocked) {
+		metrics.observeBlocked(blocked.Reason)
+	}
+}
+
+// einthusanScraper fetches and parses live Einthusan pages.
+type einthusanScraper struct{}
+
+var upstream scraper.Scraper = einthusanScraper{}
+
+// ScrapeListing fetches and parses one results page. Concurrent calls for the
+// same URL share a single fetch; see listingFlights.
+func (einthusanScraper) ScrapeListing(ctx context.Context, url string) (scraper.Listing, error) {
+	result, err, shared := listingFlights.do(ctx, url, func(ctx context.Context) (scraper.Listing, error) {
+		stats.upstream.Add(1)
+		return fetchListing(ctx, url)
+	})
+	if shared {
+		stats.coalesced.Add(1)
+	}
+	return result, err
+}
+
+// fetchListing fetches and parses one listing page. Once ctx is cancelled,
+// because every client waiting on it has hung up, it stops where it is and
+// the scrape isn't counted as a failure.
+func fetchListing(ctx context.Context, url string) (scraper.Listing, error) {
+	start := time.Now()
+	res, err := fetchUpstream(ctx, url)
+	if ctx.Err() != nil {
+		if res != nil {
+			res.Body.Close()
+		}
+		slog.Debug("upstream scrape cancelled", "request_id", requestID(ctx), "url", url, "duration_ms", time.Since(start).Milliseconds())
+		return scraper.Listing{}, ctx.Err()
+	}
+	if err != nil {
+		metrics.observeScrape(time.Since(start), err)
+		slog.Warn("upstream scrape failed", "request_id", requestID(ctx), "url", url, "duration_ms", time.Since(start).Milliseconds(), "error", err)
+		return scraper.Listing{}, err
+	}
+	defer res.Body.Close()
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if ctx.Err() != nil {
+		return scraper.Listing{}, ctx.Err()
+	}
+	if err != nil {
+		metrics.observeScrape(time.Since(start), err)
+		return scraper.Listing{}, err
+	}
+	result, err := scraper.ParseListing(res, doc)
+	metrics.observeScrape(time.Since(start), err)
+	observeBlock(err)
+	if err != nil {
+		recentErrors.add(url, err)
+		return scraper.Listing{}, err
+	}
+	if result.Degraded {
+		metrics.observeDegraded()
+		slog.Warn("upstream scrape degraded: results listed but none matched the selectors", "request_id", requestID(ctx), "url", url)
+	}
+	slog.Info("upstream scrape", "request_id", requestID(ctx), "url", url, "status", res.StatusCode, "duration_ms", time.Since(start).Milliseconds(), "movies", len(result.Movies))
+	return result, nil
+}
+
+func (einthusanScraper) ScrapeMovieDetail(ctx context.Context, language, id string) (*models.MovieDetail, error) {
+	return scrapeMovieDetail(ctx, language, id)
+}
+
+func (einthusanScraper) CheckAvailability(ctx context.Context, language, id string) (bool, error) {
+	return checkAvailability(ctx, language, id)
+}
+
+// watchUrl is the canonical watch page for a movie ID. It is built on the
+// primary mirror, like every URL the API builds, and fetchUpstream moves it
+// onto whichever mirror is answering.
+func watchUrl(language, id string) string {
+	return fmt.Sprintf("%s/movie/watch/%s/?lang=%s", scraper.Einthusan.BaseURL(), url.PathEscape(id), url.QueryEscape(language))
+}
+
+// movieTarget reads the language and movie ID from a watch page URL or a
+// bare movie ID, with fallback as the language of IDs and of URLs without
+// a lang parameter. Only the ID is taken from a URL, so links from an old
+// Einthusan domain still resolve.
+func movieTarget(input, fallback string) (language, id string, err error) {
+	input = strings.TrimSpace(input)
+	language = fallback
+	if strings.Contains(input, "/") {
+		id = scraper.MovieID(input)
+		if id == "" {
+			return "", "", errors.New("not a watch page URL")
+		}
+		if u, err := url.Parse(input); err == nil && u.Query().Get("lang") != "" {
+			language = strings.ToLower(u.Query().Get("lang"))
+		}
+	} else {
+		id = input
+	}
+	switch {
+	case id == "":
+		return "", "", errors.New("empty movie ID")
+	case language == "":
+		return "", "", errors.New("no language; pass ?language= for bare IDs")
+	case !slices.Contains(knownLanguages(), language):
+		return "", "", fmt.Errorf("unsupported language %q", language)
+	}
+	return language, id, nil
+}
+
+// errStreamingDisabled is returned by the stream extractors in builds
+// without the "stream" tag; see stream.go.
+var errStreamingDisabled = errors.New("stream extraction is not included in this build (rebuild with -tags stream)")
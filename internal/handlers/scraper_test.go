@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// fixtureDir holds the saved pages, which live beside the parser.
+var fixtureDir = filepath.Join("..", "scraper", "testdata")
+
+// fixtureScraper serves every listing from one saved page, so handlers can be
+// exercised without a network.
+type fixtureScraper struct {
+	t    *testing.T
+	page string
+}
+
+func (fs fixtureScraper) ScrapeListing(ctx context.Context, url string) (scraper.Listing, error) {
+	f, err := os.Open(filepath.Join(fixtureDir, fs.page))
+	if err != nil {
+		fs.t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		fs.t.Fatal(err)
+	}
+	return scraper.ParseListing(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, doc)
+}
+
+func (fs fixtureScraper) ScrapeMovieDetail(ctx context.Context, language, id string) (*models.MovieDetail, error) {
+	return nil, scraper.ErrNotFound
+}
+
+func (fs fixtureScraper) CheckAvailability(ctx context.Context, language, id string) (bool, error) {
+	return false, scraper.ErrNotFound
+}
+
+// useFixtures swaps upstream for a fixtureScraper serving page for the rest
+// of the test, starting from an empty cache.
+func useFixtures(t *testing.T, page string) {
+	t.Helper()
+	previous := upstream
+	upstream = fixtureScraper{t: t, page: page}
+	cache.flush("", "")
+	t.Cleanup(func() {
+		upstream = previous
+		cache.flush("", "")
+	})
+}
+
+func TestBrowseFromFixture(t *testing.T) {
+	useFixtures(t, "results.html")
+	w := httptest.NewRecorder()
+	NewRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/language/tamil?fields=full", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var resp models.BrowseResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.PageSize != scraper.PageSize() {
+		t.Errorf("page_size = %d, want PageSize() (%d)", resp.PageSize, scraper.PageSize())
+	}
+	if resp.Count != 20 || len(resp.Movies) != 20 || resp.Movies[0].Title != "Theri" || resp.Movies[0].Synopsis == "" || !resp.HasMore {
+		t.Errorf("got count %d, %d movies, first %+v, has_more %v", resp.Count, len(resp.Movies), resp.Movies[0], resp.HasMore)
+	}
+}
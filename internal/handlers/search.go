@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// defaultTitleSimilarity is the minimum similarity (0..1) at which two titles
+// from different languages are treated as the same film. TITLE_SIMILARITY
+// overrides it; 1 only merges titles that normalize identically.
+const defaultTitleSimilarity = 0.9
+
+var titleSimilarityDefault = envFloat("TITLE_SIMILARITY", defaultTitleSimilarity)
+
+// CombinedMovie is a film found in one or more languages of a cross-language search.
+type CombinedMovie struct {
+	models.MovieEntry
+	Languages []string `json:"languages"`
+}
+
+// normalizeTitle lowercases a title and reduces it to letters and digits
+// separated by single spaces, so punctuation and spacing don't matter.
+func normalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// titleSimilarity scores two titles between 0 and 1 using the edit distance
+// of their normalized forms.
+func titleSimilarity(a, b string) float64 {
+	na, nb := normalizeTitle(a), normalizeTitle(b)
+	if na == nb {
+		return 1
+	}
+	longest := max(len([]rune(na)), len([]rune(nb)))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(fuzzy.LevenshteinDistance(na, nb))/float64(longest)
+}
+
+// mergeSimilarTitles folds movies whose titles are at least threshold similar
+// into the first occurrence, accumulating the languages they were found in.
+// Only listings from different languages are folded, since one language
+// never lists the same film twice, and titles whose numbers differ are kept
+// apart so sequels don't collapse into each other.
+func mergeSimilarTitles(movies []CombinedMovie, threshold float64) []CombinedMovie {
+	merged := make([]CombinedMovie, 0, len(movies))
+	for _, movie := range movies {
+		matched := false
+		for i := range merged {
+			if slices.ContainsFunc(movie.Languages, func(l string) bool { return slices.Contains(merged[i].Languages, l) }) ||
+				!slices.Equal(titleNumbers(merged[i].Title), titleNumbers(movie.Title)) {
+				continue
+			}
+			if titleSimilarity(merged[i].Title, movie.Title) >= threshold {
+				merged[i].Languages = appendMissing(merged[i].Languages, movie.Languages...)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			merged = append(merged, movie)
+		}
+	}
+	return merged
+}
+
+// titleNumbers returns the digit runs in a title, e.g. ["2"] for "Baahubali 2".
+func titleNumbers(title string) []string {
+	return strings.FieldsFunc(title, func(r rune) bool { return !unicode.IsDigit(r) })
+}
+
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// searchListing runs query against one language. Known alternate spellings
+// are searched too and merged in; they are best-effort, so a failed variant
+// just contributes nothing.
+func searchListing(ctx context.Context, language, query string, page int) (scraper.Listing, error) {
+	result, err := cachedScrape(ctx, language, searchUrl(language, query, page))
+	if err != nil {
+		return scraper.Listing{}, err
+	}
+	_, variants := queryVariants(query)
+	// Einthusan only matches Latin titles, so a query in Tamil, Telugu or
+	// Devanagari script is also searched romanized.
+	if roman := romanize(query); roman != query {
+		variants = append(variants, roman)
+	}
+	for _, variant := range variants {
+		extra, err := cachedScrape(ctx, language, searchUrl(language, variant, page))
+		if err == nil {
+			result.Movies = mergeMovies(result.Movies, extra.Movies)
+			result.HasNext = result.HasNext || extra.HasNext
+		}
+	}
+	return result, nil
+}
+
+// searchUrl builds the upstream search URL for one results page. Spaces
+// become "+" and anything else special is percent-escaped.
+func searchUrl(language, query string, page int) string {
+	return pageUrl(fmt.Sprintf("%s/movie/results/?lang=%s&query=%s", scraper.Einthusan.BaseURL(), language, url.QueryEscape(query)), page)
+}
+
+// mergeMovies appends the movies from extra that movies doesn't already have.
+func mergeMovies(movies, extra []models.MovieEntry) []models.MovieEntry {
+	seen := make(map[string]bool, len(movies))
+	for _, m := range movies {
+		seen[m.Key()] = true
+	}
+	for _, m := range extra {
+		if !seen[m.Key()] {
+			seen[m.Key()] = true
+			movies = append(movies, m)
+		}
+	}
+	return movies
+}
+
+// searchRanking selects how rankBy orders results; SEARCH_RANKING sets it.
+//
+//   - "levenshtein" (the default): fuzzy.RankMatch gives an edit distance
+//     (lower is closer, -1 for no match), so matches come first in ascending
+//     distance. When fuzzy scores tie, which is what happens when they are
+//     uniformly -1, an exact/prefix/substring match on the normalized title
+//     decides, so the obvious hit still floats to the top.
+//   - "token": exact, then prefix, then substring matches on the normalized
+//     title come first, then titles sharing more of the query's words.
+//     It suits multi-word queries whose words appear out of order.
+//
+// Either way min_score filters on the edit distance; see filterByScore.
+var searchRanking = loadSearchRanking()
+
+func loadSearchRanking() string {
+	ranking := strings.ToLower(strings.TrimSpace(Setting("SEARCH_RANKING")))
+	switch ranking {
+	case "":
+		return "levenshtein"
+	case "levenshtein", "token":
+		return ranking
+	}
+	log.Printf("config: ignoring SEARCH_RANKING=%q, must be levenshtein or token", ranking)
+	return "levenshtein"
+}
+
+// rankMovies orders movies by relevance to query, as searchRanking says.
+// Query and titles are compared by their foldTitle keys, so transliterated
+// spellings match, unless strict asks for the titles as written.
+func rankMovies(query string, movies []models.MovieEntry, strict bool) {
+	rankBy(query, movies, func(m models.MovieEntry) string { return m.Title }, strict)
+}
+
+// rankBy is rankMovies for any item with a title.
+func rankBy[T any](query string, items []T, title func(T) string, strict bool) {
+	lower, normalize := strings.ToLower, normalizeTitle
+	if !strict {
+		lower, normalize = foldTitle, foldTitle
+	}
+	q := lower(query)
+	nq := strings.ReplaceAll(normalize(query), " ", "")
+	words := strings.Fields(normalize(query))
+	type ranked struct {
+		item     T
+		distance int
+		fallback int
+		shared   int
+	}
+	scored := make([]ranked, len(items))
+	for i, item := range items {
+		t, nt := title(item), normalize(title(item))
+		scored[i] = ranked{
+			item:     item,
+			distance: fuzzy.RankMatch(q, lower(t)),
+			fallback: substringScore(nq, strings.ReplaceAll(nt, " ", "")),
+			shared:   sharedWords(words, strings.Fields(nt)),
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		a, b := scored[i], scored[j]
+		if searchRanking == "token" {
+			if a.fallback != b.fallback {
+				return a.fallback > b.fallback
+			}
+			return a.shared > b.shared
+		}
+		if a.distance != b.distance {
+			if a.distance < 0 || b.distance < 0 {
+				return b.distance < 0
+			}
+			return a.distance < b.distance
+		}
+		return a.fallback > b.fallback
+	})
+	for i := range scored {
+		items[i] = scored[i].item
+	}
+}
+
+// sharedWords counts the query words that also appear in title.
+func sharedWords(query, title []string) int {
+	n := 0
+	for _, word := range query {
+		if slices.Contains(title, word) {
+			n++
+		}
+	}
+	return n
+}
+
+// sortMovies reorders ranked results by sort=: "relevance" keeps the ranking,
+// "title" sorts alphabetically and "year" puts the newest first, with
+// undated movies last. Ties keep their relevance order.
+func sortMovies(movies []models.MovieEntry, order string) {
+	switch order {
+	case "title":
+		slices.SortStableFunc(movies, func(a, b models.MovieEntry) int {
+			return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		})
+	case "year":
+		// Descending, which leaves undated (year 0) movies at the end.
+		slices.SortStableFunc(movies, func(a, b models.MovieEntry) int { return cmp.Compare(b.Year, a.Year) })
+	}
+}
+
+// filterByScore keeps the movies whose title is within maxDistance of query,
+// keeping the order. The score is fuzzy.RankMatch's edit distance, so lower
+// is closer: 0 keeps only exact matches, and non-matches (-1) are always
+// dropped. The result is never nil. As in rankMovies, strict compares the
+// titles as written.
+func filterByScore(query string, movies []models.MovieEntry, maxDistance int, strict bool) []models.MovieEntry {
+	lower := foldTitle
+	if strict {
+		lower = strings.ToLower
+	}
+	q := lower(query)
+	kept := []models.MovieEntry{}
+	for _, m := range movies {
+		if distance := fuzzy.RankMatch(q, lower(m.Title)); distance >= 0 && distance <= maxDistance {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// substringScore rates how directly title contains query: 3 for an exact
+// match, 2 for a prefix, 1 for a substring, 0 otherwise.
+func substringScore(query, title string) int {
+	switch {
+	case query == "":
+		return 0
+	case title == query:
+		return 3
+	case strings.HasPrefix(title, query):
+		return 2
+	case strings.Contains(title, query):
+		return 1
+	}
+	return 0
+}
+
+// searchMovies searches one language, ranking results by how closely their
+// titles match q. min_score is optional: it is the largest edit distance from
+// q a title may have, and without it every scraped movie is returned. sort= reorders the ranked results and limit= keeps only the
+// first that many.
+func searchMovies(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	// Collapse runs of whitespace (spaces, tabs, newlines) so "a  b" and
+	// "   " don't turn into bogus upstream queries.
+	query := strings.Join(strings.Fields(c.Query("q")), " ")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if resp, ok := searchResults(c, language, query, page); ok {
+		respond(c, http.StatusOK, resp)
+	}
+}
+
+// searchResults validates searchMovies' options and runs the search for
+// query's page. It responds itself when it fails.
+func searchResults(c *gin.Context, language, query string, page int) (models.SearchResponse, bool) {
+	if query == "" {
+		respondError(c, http.StatusBadRequest, "invalid_query", "q is required")
+		return models.SearchResponse{}, false
+	}
+	minScore, filter := 0, c.Query("min_score") != ""
+	if filter {
+		var err error
+		if minScore, err = strconv.Atoi(c.Query("min_score")); err != nil || minScore < 0 {
+			respondError(c, http.StatusBadRequest, "invalid_parameter", "min_score must be a non-negative integer")
+			return models.SearchResponse{}, false
+		}
+	}
+
+	order := c.DefaultQuery("sort", "relevance")
+	if order != "relevance" && order != "title" && order != "year" {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "sort must be relevance, title or year")
+		return models.SearchResponse{}, false
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "invalid_parameter", "limit must be a positive integer")
+			return models.SearchResponse{}, false
+		}
+	}
+
+	enrich, ok := wantsEnrich(c)
+	if !ok {
+		return models.SearchResponse{}, false
+	}
+	full, ok := wantsFullFields(c)
+	if !ok {
+		return models.SearchResponse{}, false
+	}
+	strict, ok := wantsStrict(c)
+	if !ok {
+		return models.SearchResponse{}, false
+	}
+
+	source, ok := requireProvider(c, language)
+	if !ok {
+		return models.SearchResponse{}, false
+	}
+
+	resp, err := rankedSearch(c.Request.Context(), source, language, query, page, searchOptions{
+		minScore: minScore, filter: filter, order: order, limit: limit, enrich: enrich, strict: strict,
+	})
+	if err != nil {
+		respondScrapeError(c, err)
+		return models.SearchResponse{}, false
+	}
+	if !full {
+		basicFields(resp.Movies)
+	}
+	return resp, true
+}
+
+// searchOptions are searchMovies' query parameters after validation.
+type searchOptions struct {
+	minScore int  // the largest edit distance kept, despite the name
+	filter   bool // apply minScore
+	order    string
+	limit    int // 0 keeps every result
+	enrich   bool
+	strict   bool // rank by titles as written, not transliteration keys
+}
+
+// rankedSearch fetches one page of search results for query and ranks,
+// filters, sorts and trims them as opts asks.
+func rankedSearch(ctx context.Context, source provider, language, query string, page int, opts searchOptions) (models.SearchResponse, error) {
+	result, err := source.search(ctx, language, query, page)
+	if err != nil {
+		return models.SearchResponse{}, err
+	}
+	canonical, _ := queryVariants(query)
+
+	// Sort results by fuzzy match for relevance
+	rankMovies(canonical, result.Movies, opts.strict)
+	reason := listingReason(result.Movies, result.Degraded, reasonNoMatches)
+	if opts.filter {
+		result.Movies = filterByScore(canonical, result.Movies, opts.minScore, opts.strict)
+		if reason == "" {
+			reason = emptyReason(result.Movies, reasonFilteredOut)
+		}
+	}
+	sortMovies(result.Movies, opts.order)
+	if opts.limit > 0 && len(result.Movies) > opts.limit {
+		result.Movies = result.Movies[:opts.limit]
+	}
+	if opts.enrich {
+		enrichMovies(ctx, language, result.Movies)
+	}
+
+	return models.SearchResponse{
+		Language: language,
+		Movies:   result.Movies,
+		Query:    query,
+		Page:     page,
+		NextPage: nextPageAfter(page, result.HasNext),
+		HasMore:  result.HasNext,
+
+		EstimatedTotal: result.Total,
+		Reason:         reason,
+		ScrapeDegraded: result.Degraded,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+
+	"apppp/internal/models"
+)
+
+func TestMergeSimilarTitles(t *testing.T) {
+	tamil := CombinedMovie{MovieEntry: models.MovieEntry{ID: "t1", Title: "Kaththi"}, Languages: []string{"tamil"}}
+	telugu := CombinedMovie{MovieEntry: models.MovieEntry{ID: "e1", Title: "Kathi"}, Languages: []string{"telugu"}}
+	similarity := titleSimilarity(tamil.Title, telugu.Title)
+
+	tests := []struct {
+		name      string
+		movies    []CombinedMovie
+		threshold float64
+		want      [][]string // each merged movie's languages
+	}{
+		{"merged below their similarity", []CombinedMovie{tamil, telugu}, similarity - 0.05, [][]string{{"tamil", "telugu"}}},
+		{"kept apart above it", []CombinedMovie{tamil, telugu}, similarity + 0.05, [][]string{{"tamil"}, {"telugu"}}},
+		{"same language never merged", []CombinedMovie{tamil, {MovieEntry: models.MovieEntry{ID: "t2", Title: "Kaththi"}, Languages: []string{"tamil"}}}, 0, [][]string{{"tamil"}, {"tamil"}}},
+		{"sequels kept apart", []CombinedMovie{
+			{MovieEntry: models.MovieEntry{ID: "t3", Title: "Baahubali 2"}, Languages: []string{"tamil"}},
+			{MovieEntry: models.MovieEntry{ID: "e3", Title: "Baahubali"}, Languages: []string{"telugu"}},
+		}, 0.5, [][]string{{"tamil"}, {"telugu"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mergeSimilarTitles(slices.Clone(tt.movies), tt.threshold)
+			var got [][]string
+			for _, m := range merged {
+				got = append(got, m.Languages)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("merged languages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankFallsBackToSubstringMatch(t *testing.T) {
+	// "spider-man" isn't a fuzzy match for any of these as written, since none
+	// has the hyphen, so every fuzzy score ties at -1; only one title contains
+	// the query once punctuation is dropped.
+	movies := []models.MovieEntry{{Title: "Iron Man"}, {Title: "Batman Begins"}, {Title: "The Amazing Spiderman"}}
+	for _, m := range movies {
+		if score := fuzzy.RankMatch("spider-man", strings.ToLower(m.Title)); score != -1 {
+			t.Fatalf("fuzzy score for %q = %d, want -1", m.Title, score)
+		}
+	}
+	rankMovies("spider-man", movies, true)
+	if movies[0].Title != "The Amazing Spiderman" {
+		t.Errorf("ranked %v, want the substring match first", movies)
+	}
+}
+
+func TestFilterByScoreKeepsCloseMatches(t *testing.T) {
+	movies := []models.MovieEntry{{Title: "Theri"}, {Title: "Theri 2"}, {Title: "Theriyaama"}, {Title: "Thenali"}}
+	tests := []struct {
+		maxDistance int
+		want        []string
+	}{
+		{0, []string{"Theri"}},
+		{2, []string{"Theri", "Theri 2"}},
+		{10, []string{"Theri", "Theri 2", "Theriyaama"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range filterByScore("theri", movies, tt.maxDistance, true) {
+			got = append(got, m.Title)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("filterByScore(%d) = %q, want %q", tt.maxDistance, got, tt.want)
+		}
+	}
+}
+
+func TestSearchMinScoreWithRelevance(t *testing.T) {
+	fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(fixtureDir, "results.html"))
+	})
+	// Against "kathi", Kaithi is one edit away and Kaththi two; nothing else
+	// on the page matches.
+	tests := []struct {
+		ranking, minScore string
+		want              []string
+	}{
+		{"levenshtein", "0", nil},
+		{"levenshtein", "1", []string{"Kaithi"}},
+		{"levenshtein", "2", []string{"Kaithi", "Kaththi"}},
+		{"token", "1", []string{"Kaithi"}},
+	}
+	router := NewRouter()
+	for _, tt := range tests {
+		previous := searchRanking
+		searchRanking = tt.ranking
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search/tamil?q=kathi&strict=true&sort=relevance&min_score="+tt.minScore, nil))
+		searchRanking = previous
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d; body %s", w.Code, w.Body)
+		}
+		var resp models.SearchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range resp.Movies {
+			got = append(got, m.Title)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s ranking, min_score=%s: got %q, want %q", tt.ranking, tt.minScore, got, tt.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search/tamil?q=kathi&min_score=-1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("min_score=-1: status = %d, want 400", w.Code)
+	}
+}
+
+func TestSynonymAliases(t *testing.T) {
+	previous := synonymGroups
+	synonymGroups = map[string][]string{}
+	t.Cleanup(func() { synonymGroups = previous })
+	path := filepath.Join(t.TempDir(), "synonyms.json")
+	if err := os.WriteFile(path, []byte(`{"Kaithi": ["Kaidhi"], "vikram": ["Vickram", "Vikkram"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadSynonyms(path); err != nil {
+		t.Fatalf("loadSynonyms: %v", err)
+	}
+
+	tests := []struct {
+		query, wantCanonical string
+		wantVariants         []string
+	}{
+		{"Kaidhi", "kaithi", []string{"kaithi"}},
+		{"kaithi", "kaithi", []string{"kaidhi"}},
+		{"VICKRAM", "vikram", []string{"vikram", "vikkram"}},
+		{"Theri", "Theri", nil},
+	}
+	for _, tt := range tests {
+		canonical, variants := queryVariants(tt.query)
+		if canonical != tt.wantCanonical || !slices.Equal(variants, tt.wantVariants) {
+			t.Errorf("queryVariants(%q) = %q, %v; want %q, %v", tt.query, canonical, variants, tt.wantCanonical, tt.wantVariants)
+		}
+	}
+
+	// Einthusan only knows the canonical spelling, but the alias finds it too.
+	fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("query") == "kaithi" {
+			http.ServeFile(w, r, filepath.Join(fixtureDir, "results.html"))
+			return
+		}
+		w.Write([]byte(`<html><body><section id="UIMovieSummary"><ul></ul></section></body></html>`))
+	})
+	result, err := searchListing(t.Context(), "tamil", "kaidhi", 1)
+	if err != nil {
+		t.Fatalf("searchListing: %v", err)
+	}
+	if len(result.Movies) != 20 {
+		t.Errorf("searching the alias found %d movies, want the canonical spelling's 20", len(result.Movies))
+	}
+}
+
+func TestSearchQueryWhitespace(t *testing.T) {
+	var sent []string
+	var mu sync.Mutex
+	fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.URL.Query().Get("query"))
+		mu.Unlock()
+		http.ServeFile(w, r, filepath.Join(fixtureDir, "results.html"))
+	})
+	tests := []struct {
+		q         string
+		wantCode  int
+		wantQuery string // as Einthusan receives it
+	}{
+		{"   ", http.StatusBadRequest, ""},
+		{"\t\n", http.StatusBadRequest, ""},
+		{"a  b", http.StatusOK, "a b"},
+		{" a\tb\nc ", http.StatusOK, "a b c"},
+	}
+	router := NewRouter()
+	for _, tt := range tests {
+		for _, target := range []string{"/search/tamil?q=", "/search?languages=tamil&q="} {
+			mu.Lock()
+			sent = nil
+			mu.Unlock()
+			cache.flush("", "")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target+url.QueryEscape(tt.q), nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("%s%q: status = %d, want %d; body %s", target, tt.q, w.Code, tt.wantCode, w.Body)
+				continue
+			}
+			mu.Lock()
+			if tt.wantQuery == "" && len(sent) > 0 {
+				t.Errorf("%s%q reached upstream as %q", target, tt.q, sent)
+			}
+			if tt.wantQuery != "" && (len(sent) != 1 || sent[0] != tt.wantQuery) {
+				t.Errorf("%s%q reached upstream as %q, want [%q]", target, tt.q, sent, tt.wantQuery)
+			}
+			mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultShutdownGraceSeconds = 10
+	defaultReadTimeoutSeconds   = 15
+	defaultWriteTimeoutSeconds  = 0
+	defaultIdleTimeoutSeconds   = 120
+)
+
+// NewServer wraps handler in an http.Server with SERVER_READ_TIMEOUT_SECONDS
+// for reading a request, SERVER_WRITE_TIMEOUT_SECONDS for writing the
+// response and SERVER_IDLE_TIMEOUT_SECONDS for keep-alive connections. A
+// write timeout of 0, the default, leaves responses unbounded, since /export
+// streams for as long as the listing takes to walk.
+func NewServer(addr string, handler http.Handler) *http.Server {
+	seconds := func(name string, def int) time.Duration {
+		return time.Duration(max(EnvInt(name, def), 0)) * time.Second
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: seconds("SERVER_READ_TIMEOUT_SECONDS", defaultReadTimeoutSeconds),
+		ReadTimeout:       seconds("SERVER_READ_TIMEOUT_SECONDS", defaultReadTimeoutSeconds),
+		WriteTimeout:      seconds("SERVER_WRITE_TIMEOUT_SECONDS", defaultWriteTimeoutSeconds),
+		IdleTimeout:       seconds("SERVER_IDLE_TIMEOUT_SECONDS", defaultIdleTimeoutSeconds),
+	}
+}
+
+// Serve runs server until SIGINT or SIGTERM, then stops accepting new
+// connections and waits up to SHUTDOWN_GRACE_SECONDS for in-flight requests,
+// so a deploy doesn't cut scrapes off mid-response. Requests still running
+// when the grace period ends have their contexts cancelled, which aborts
+// their upstream scrapes. The cache is saved to CACHE_DIR once they are done.
+func Serve(server *http.Server) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownGracePeriod := time.Duration(max(EnvInt("SHUTDOWN_GRACE_SECONDS", defaultShutdownGraceSeconds), 0)) * time.Second
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server.BaseContext = func(net.Listener) context.Context { return baseCtx }
+	server.RegisterOnShutdown(releases.close)
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("server: listening on %s", server.Addr)
+		errc <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		log.Fatalf("server: %v", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Printf("server: shutting down, waiting up to %s for in-flight requests", shutdownGracePeriod)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server: forced shutdown: %v", err)
+		cancelRequests()
+		server.Close()
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("server: %v", err)
+	}
+	persistCache()
+	downloads.close()
+	if watchlistDB != nil {
+		watchlistDB.Close()
+	}
+	log.Printf("server: stopped")
+}
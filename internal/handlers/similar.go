@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const (
+	defaultSimilarSize = 20
+	maxSimilarSize     = 50
+	// similarCast and similarGenres bound the listings one request scrapes:
+	// the first few billed cast members and genres say the most.
+	similarCast   = 3
+	similarGenres = 2
+
+	// A shared cast member says more about a movie than a shared genre.
+	castWeight  = 3
+	genreWeight = 1
+)
+
+// SimilarMovie is a recommended movie and why it was picked.
+type SimilarMovie struct {
+	models.MovieEntry
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons"` // e.g. "cast:Vijay", "genre:action"
+}
+
+type SimilarResponse struct {
+	Language string         `json:"language"`
+	MovieID  string         `json:"movie_id"`
+	Title    string         `json:"title"`
+	Movies   []SimilarMovie `json:"movies"`
+	Reason   string         `json:"reason,omitempty"` // Why Movies is empty
+}
+
+// similarSource is one listing recommendations are drawn from.
+type similarSource struct {
+	url, reason string
+	weight      int
+}
+
+// similarMovies answers /similar/:language/:movieid with a "more like this"
+// list. It reads the movie's cast and genres, scrapes the filmographies of
+// the first few cast members and the first page of each genre, and ranks the
+// movies found by weighted overlap. A listing that fails to load is skipped.
+func similarMovies(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	source, ok := requireProvider(c, language)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSimilarSize)))
+	if err != nil || limit < 1 || limit > maxSimilarSize {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", fmt.Sprintf("limit must be between 1 and %d", maxSimilarSize))
+		return
+	}
+	full, ok := wantsFullFields(c)
+	if !ok {
+		return
+	}
+	id := c.Param("movieid")
+	detail, err := source.details(c.Request.Context(), language, id)
+	if errors.Is(err, scraper.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+		return
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+
+	sources := similarSources(language, detail)
+	results := make([]scraper.Listing, len(sources))
+	errs := make([]error, len(sources))
+	sem := make(chan struct{}, maxConcurrentPages)
+	var wg sync.WaitGroup
+	for i, s := range sources {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = cachedScrape(c.Request.Context(), language, s.url)
+		})
+	}
+	wg.Wait()
+	if len(sources) > 0 && !slices.Contains(errs, nil) {
+		respondScrapeError(c, errs[0])
+		return
+	}
+
+	movies := rankSimilar(id, sources, results, errs)
+	movies = movies[:min(limit, len(movies))]
+	if !full {
+		for i := range movies {
+			movies[i].Duration, movies[i].Synopsis, movies[i].Views = "", "", 0
+		}
+	}
+	reason := ""
+	if len(movies) == 0 {
+		reason = reasonNoMatches
+	}
+	respond(c, http.StatusOK, SimilarResponse{Language: language, MovieID: id, Title: detail.Title, Movies: movies, Reason: reason})
+}
+
+// similarSources picks the listings to draw from: the filmographies of the
+// first cast members with a profile, then the first genres Einthusan's
+// finder knows.
+func similarSources(language string, detail *models.MovieDetail) []similarSource {
+	var sources []similarSource
+	for _, member := range detail.Cast {
+		if len(sources) == similarCast {
+			break
+		}
+		if member.ID != "" {
+			sources = append(sources, similarSource{url: actorUrl(language, member.ID), reason: "cast:" + member.Name, weight: castWeight})
+		}
+	}
+	genres := 0
+	for _, genre := range detail.Genres {
+		genre = strings.ToLower(strings.TrimSpace(genre))
+		if genres < similarGenres && slices.Contains(knownGenres, genre) {
+			sources = append(sources, similarSource{url: genreUrl(language, genre), reason: "genre:" + genre, weight: genreWeight})
+			genres++
+		}
+	}
+	return sources
+}
+
+// rankSimilar scores each movie in the loaded listings by the weights of
+// the listings it appears in, leaving out the movie itself. Ties keep the
+// order the movies were first seen in.
+func rankSimilar(id string, sources []similarSource, results []scraper.Listing, errs []error) []SimilarMovie {
+	var ranked []SimilarMovie
+	index := make(map[string]int)
+	for i, result := range results {
+		if errs[i] != nil {
+			continue
+		}
+		for _, m := range result.Movies {
+			key := m.Key()
+			if m.ID == id {
+				continue
+			}
+			j, seen := index[key]
+			if !seen {
+				j = len(ranked)
+				index[key] = j
+				ranked = append(ranked, SimilarMovie{MovieEntry: m, Reasons: []string{}})
+			}
+			if !slices.Contains(ranked[j].Reasons, sources[i].reason) {
+				ranked[j].Score += sources[i].weight
+				ranked[j].Reasons = append(ranked[j].Reasons, sources[i].reason)
+			}
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b SimilarMovie) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return ranked
+}
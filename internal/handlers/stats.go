@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrapeStats counts how listing lookups were satisfied, to show how much
+// upstream traffic the caching layers save.
+type scrapeStats struct {
+	upstream  atomic.Int64
+	cacheHits atomic.Int64
+	memoHits  atomic.Int64
+	coalesced atomic.Int64 // waited on another request's identical scrape
+}
+
+var stats scrapeStats
+
+type StatsResponse struct {
+	UpstreamScrapes  int64   `json:"upstream_scrapes"`
+	CacheHits        int64   `json:"cache_hits"`
+	RequestMemoHits  int64   `json:"request_memo_hits"`
+	CoalescedScrapes int64   `json:"coalesced_scrapes"`
+	SavedRatio       float64 `json:"saved_ratio"`
+}
+
+func (s *scrapeStats) snapshot() StatsResponse {
+	resp := StatsResponse{
+		UpstreamScrapes:  s.upstream.Load(),
+		CacheHits:        s.cacheHits.Load(),
+		RequestMemoHits:  s.memoHits.Load(),
+		CoalescedScrapes: s.coalesced.Load(),
+	}
+	saved := resp.CacheHits + resp.RequestMemoHits + resp.CoalescedScrapes
+	if total := saved + resp.UpstreamScrapes; total > 0 {
+		resp.SavedRatio = float64(saved) / float64(total)
+	}
+	return resp
+}
+
+func showStats(c *gin.Context) {
+	respond(c, http.StatusOK, stats.snapshot())
+}
@@ -0,0 +1,23 @@
+//go:build !stream
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// streamingEnabled reports whether this build resolves streams.
+const streamingEnabled = false
+
+// resolveStreams is unavailable without the "stream" build tag; see stream.go.
+func resolveStreams(ctx context.Context, language, id string) (*StreamResponse, error) {
+	return nil, errStreamingDisabled
+}
+
+// decodePlayerPayload is unavailable without the "stream" build tag; see
+// stream.go. Plain player links are still read by extractStreamUrl.
+func decodePlayerPayload(player *goquery.Selection) (string, error) {
+	return "", errStreamingDisabled
+}
@@ -0,0 +1,133 @@
+//go:build stream
+
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// encodeEInth is decodeEInth's inverse, for building player payloads.
+func encodeEInth(data []byte) string {
+	b := base64.StdEncoding.EncodeToString(data)
+	return b[:10] + "xx" + b[11:] + b[10:11]
+}
+
+// streamHandshake serves a watch page and the player's ajax endpoint, which
+// only answers a request carrying the page's session cookie.
+func streamHandshake(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/ajax/") {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" || r.FormValue("gorilla.csrf.Token") != "csrf" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		links, _ := json.Marshal(map[string]string{"MP4Link": "//1.2.3.4/hd/A00x.mp4", "HLSLink": "//1.2.3.4/A00x.m3u8"})
+		json.NewEncoder(w).Encode(map[string]any{"Data": map[string]string{"EJLinks": encodeEInth(links)}})
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+	w.Write([]byte(`<html data-pageid="csrf"><body><div id="UIVideoPlayer" data-ejpingables="tok"></div></body></html>`))
+}
+
+func TestStreamHandshake(t *testing.T) {
+	hits := fakeUpstream(t, streamHandshake)
+	w := httptest.NewRecorder()
+	NewRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream/tamil/A00x", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body %s", w.Code, w.Body)
+	}
+	var got StreamResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []StreamSource{
+		{URL: "https://cdn1.einthusan.io/hd/A00x.mp4", Type: "mp4", Quality: "hd"},
+		{URL: "https://cdn1.einthusan.io/A00x.m3u8", Type: "hls", Quality: "auto"},
+	}
+	if len(got.Sources) != len(want) || got.Sources[0] != want[0] || got.Sources[1] != want[1] {
+		t.Errorf("sources = %+v, want %+v", got.Sources, want)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("upstream hit %d times, want the page and the ajax call", n)
+	}
+}
+
+func TestStreamUsesFetchPipeline(t *testing.T) {
+	t.Run("missing movies are remembered", func(t *testing.T) {
+		hits := fakeUpstream(t, http.NotFound)
+		router := NewRouter()
+		for range 2 {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream/tamil/A00x", nil))
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want 404", w.Code)
+			}
+		}
+		if n := hits.Load(); n != 1 {
+			t.Errorf("upstream hit %d times, want 1", n)
+		}
+	})
+
+	t.Run("failures trip the breaker", func(t *testing.T) {
+		hits := fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		breaker.threshold = 2
+		router := NewRouter()
+		for range 3 {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream/tamil/A00x", nil))
+			if w.Code < http.StatusInternalServerError {
+				t.Fatalf("status = %d, want a 5xx", w.Code)
+			}
+		}
+		if n := hits.Load(); n != 2 {
+			t.Errorf("upstream hit %d times, want 2; the third request should fail fast", n)
+		}
+		if state := breaker.state(); state != "open" {
+			t.Errorf("breaker is %s, want open", state)
+		}
+	})
+}
+
+// A playlist resolves every movie it lists, and each of those fetches is
+// paid for like the fan-out in TestFanOutChargesEveryFetch.
+func TestPlaylistChargesEveryFetch(t *testing.T) {
+	listing, err := os.ReadFile(filepath.Join(fixtureDir, "results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/movie/results") {
+			w.Write(listing)
+			return
+		}
+		streamHandshake(w, r)
+	})
+	// One token lets the request in; with the listing fetch free, two more
+	// pay for one movie's page and ajax call, or two pages.
+	previous := scrapeLimiter
+	scrapeLimiter = rate.NewLimiter(rate.Every(time.Hour), 3)
+	t.Cleanup(func() { scrapeLimiter = previous })
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	w := httptest.NewRecorder()
+	NewRouter().ServeHTTP(w, httptest.NewRequestWithContext(ctx, http.MethodGet, "/playlist/tamil.m3u?limit=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d; body %s", w.Code, w.Body)
+	}
+	if n := hits.Load(); n != 3 {
+		t.Errorf("upstream hit %d times, want 3", n)
+	}
+}
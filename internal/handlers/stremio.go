@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+// The Stremio addon protocol is served beside the JSON API, so the server can
+// be installed in Stremio by its /manifest.json URL. Stremio can't send
+// headers, so when API_KEYS is set the addon routes can't authenticate;
+// leave API keys off for a server meant to be used as an addon.
+//
+// Movies are identified to Stremio as "einthusan:<language>:<id>", and each
+// language is a catalog with id "einthusan-<language>".
+const (
+	stremioIDPrefix      = "einthusan:"
+	stremioCatalogPrefix = "einthusan-"
+)
+
+type stremioManifest struct {
+	ID          string           `json:"id"`
+	Version     string           `json:"version"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Resources   []string         `json:"resources"`
+	Types       []string         `json:"types"`
+	IDPrefixes  []string         `json:"idPrefixes"`
+	Catalogs    []stremioCatalog `json:"catalogs"`
+}
+
+type stremioCatalog struct {
+	Type  string         `json:"type"`
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Extra []stremioExtra `json:"extra"`
+}
+
+type stremioExtra struct {
+	Name    string   `json:"name"`
+	Options []string `json:"options,omitempty"`
+}
+
+// stremioMeta is both the catalog preview and the full meta object; the
+// preview only fills in the first few fields.
+type stremioMeta struct {
+	ID          string           `json:"id"`
+	Type        string           `json:"type"`
+	Name        string           `json:"name"`
+	Poster      string           `json:"poster,omitempty"`
+	ReleaseInfo string           `json:"releaseInfo,omitempty"`
+	Background  string           `json:"background,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Runtime     string           `json:"runtime,omitempty"`
+	Genres      []string         `json:"genres,omitempty"`
+	Director    []string         `json:"director,omitempty"`
+	Cast        []string         `json:"cast,omitempty"`
+	Trailers    []stremioTrailer `json:"trailers,omitempty"`
+}
+
+type stremioTrailer struct {
+	Source string `json:"source"` // YouTube video ID
+	Type   string `json:"type"`
+}
+
+type stremioStream struct {
+	URL   string `json:"url"`
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+func stremioManifestHandler(c *gin.Context) {
+	manifest := stremioManifest{
+		ID:          "community.thirai.einthusan",
+		Version:     "1.0.0",
+		Name:        "Einthusan",
+		Description: "Indian movies from Einthusan: browse, search and stream by language.",
+		Resources:   []string{"catalog", "meta", "stream"},
+		Types:       []string{"movie"},
+		IDPrefixes:  []string{stremioIDPrefix},
+		Catalogs:    []stremioCatalog{},
+	}
+	for _, language := range supportedLanguages {
+		manifest.Catalogs = append(manifest.Catalogs, stremioCatalog{
+			Type:  "movie",
+			ID:    stremioCatalogPrefix + language,
+			Name:  "Einthusan " + strings.ToUpper(language[:1]) + language[1:],
+			Extra: []stremioExtra{{Name: "search"}, {Name: "genre", Options: []string{"recent", "popular"}}, {Name: "skip"}},
+		})
+	}
+	c.JSON(http.StatusOK, manifest)
+}
+
+// stremioCatalogHandler answers /catalog/movie/:id.json and
+// /catalog/movie/:id/:extra.json. Extra is Stremio's URL-encoded
+// search=, genre= and skip= arguments; skip is turned into an upstream page.
+func stremioCatalogHandler(c *gin.Context) {
+	id, extraRaw := c.Param("id"), c.Param("extra")
+	if extraRaw == "" {
+		id = strings.TrimSuffix(id, ".json")
+	} else {
+		extraRaw = strings.TrimSuffix(extraRaw, ".json")
+	}
+	language, ok := strings.CutPrefix(id, stremioCatalogPrefix)
+	if !ok {
+		respondError(c, http.StatusNotFound, "catalog_not_found", "catalog not found")
+		return
+	}
+	if language, ok = checkLanguage(c, language); !ok {
+		return
+	}
+	extra, err := url.ParseQuery(extraRaw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "malformed catalog extra")
+		return
+	}
+	skip, _ := strconv.Atoi(extra.Get("skip"))
+	page := max(skip, 0)/scraper.PageSize() + 1
+
+	var result scraper.Listing
+	if query := strings.Join(strings.Fields(extra.Get("search")), " "); query != "" {
+		result, err = searchListing(c.Request.Context(), language, query, page)
+	} else {
+		base, ok := browseUrl(language, strings.ToLower(cmp.Or(extra.Get("genre"), "recent")))
+		if !ok {
+			respondError(c, http.StatusBadRequest, "invalid_category", "genre must be recent or popular")
+			return
+		}
+		result, err = cachedScrape(c.Request.Context(), language, pageUrl(base, page))
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	metas := make([]stremioMeta, 0, len(result.Movies))
+	for _, m := range result.Movies {
+		metas = append(metas, stremioMeta{ID: stremioIDPrefix + language + ":" + m.ID, Type: "movie", Name: m.Title, Poster: m.ImgUrl, ReleaseInfo: stremioYear(m.Year)})
+	}
+	c.JSON(http.StatusOK, gin.H{"metas": metas})
+}
+
+// stremioMovie reads the "einthusan:<language>:<id>.json" param, responding
+// 404 when it isn't one of ours.
+func stremioMovie(c *gin.Context) (language, id string, ok bool) {
+	raw, _ := strings.CutSuffix(c.Param("id"), ".json")
+	rest, ok := strings.CutPrefix(raw, stremioIDPrefix)
+	if ok {
+		language, id, ok = strings.Cut(rest, ":")
+	}
+	if !ok || id == "" {
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+		return "", "", false
+	}
+	language, ok = checkLanguage(c, language)
+	return language, id, ok
+}
+
+func stremioMetaHandler(c *gin.Context) {
+	language, id, ok := stremioMovie(c)
+	if !ok {
+		return
+	}
+	detail, err := upstream.ScrapeMovieDetail(c.Request.Context(), language, id)
+	if errors.Is(err, scraper.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+		return
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	meta := stremioMeta{
+		ID:          stremioIDPrefix + language + ":" + id,
+		Type:        "movie",
+		Name:        detail.Title,
+		Poster:      detail.ImgUrl,
+		ReleaseInfo: stremioYear(detail.Year),
+		Background:  detail.PosterHD,
+		Description: detail.Synopsis,
+		Runtime:     detail.Duration,
+		Genres:      detail.Genres,
+	}
+	if detail.Director != "" {
+		meta.Director = []string{detail.Director}
+	}
+	for _, member := range detail.Cast {
+		meta.Cast = append(meta.Cast, member.Name)
+	}
+	if match := scraper.YoutubeIDPattern.FindStringSubmatch(detail.Trailer); match != nil {
+		meta.Trailers = []stremioTrailer{{Source: match[1], Type: "Trailer"}}
+	}
+	c.JSON(http.StatusOK, gin.H{"meta": meta})
+}
+
+func stremioStreamHandler(c *gin.Context) {
+	language, id, ok := stremioMovie(c)
+	if !ok {
+		return
+	}
+	resolved, err := resolveStreams(c.Request.Context(), language, id)
+	switch {
+	case errors.Is(err, errStreamingDisabled):
+		respondError(c, http.StatusNotImplemented, "streaming_disabled", err.Error())
+		return
+	case errors.Is(err, scraper.ErrNotFound):
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+		return
+	case err != nil:
+		respondScrapeError(c, err)
+		return
+	}
+	streams := make([]stremioStream, 0, len(resolved.Sources))
+	for _, source := range resolved.Sources {
+		streams = append(streams, stremioStream{URL: source.URL, Name: "Einthusan", Title: strings.ToUpper(source.Quality) + " " + strings.ToUpper(source.Type)})
+	}
+	c.JSON(http.StatusOK, gin.H{"streams": streams})
+}
+
+func stremioYear(year int) string {
+	if year == 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+const (
+	subtitleFetchTimeout = 5 * time.Second
+	maxSubtitleBytes     = 2 << 20
+	// subtitleCacheControl lets players and CDNs keep a subtitle file for a
+	// day; Einthusan doesn't revise them once published.
+	subtitleCacheControl = "public, max-age=86400"
+)
+
+var (
+	// srtTiming and vttTiming match a cue's timing line, capturing the two
+	// timestamps; VTT may leave out the hours and add cue settings after.
+	srtTiming = regexp.MustCompile(`^(\d{2}:\d{2}:\d{2},\d{3}) --> (\d{2}:\d{2}:\d{2},\d{3})`)
+	vttTiming = regexp.MustCompile(`^((?:\d{2,}:)?\d{2}:\d{2}\.\d{3}) --> ((?:\d{2,}:)?\d{2}:\d{2}\.\d{3})`)
+)
+
+// proxySubtitles answers /subtitles/:language/:movieid with one of the
+// movie's subtitle files, so players can load captions without reaching
+// Einthusan themselves. lang= picks the track (the first one by default) and
+// format=srt or format=vtt converts it; without format= it is passed through
+// as Einthusan serves it.
+func proxySubtitles(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	source, ok := requireProvider(c, language)
+	if !ok {
+		return
+	}
+	format := strings.ToLower(c.Query("format"))
+	if format != "" && format != "srt" && format != "vtt" {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "format must be srt or vtt")
+		return
+	}
+	id := c.Param("movieid")
+	detail, err := source.details(c.Request.Context(), language, id)
+	if errors.Is(err, scraper.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+		return
+	}
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	track, ok := pickSubtitle(detail.Subtitles, c.Query("lang"))
+	if !ok {
+		respondError(c, http.StatusNotFound, "subtitles_not_found", "no subtitles for this movie")
+		return
+	}
+	target, err := url.Parse(track.URL)
+	if err == nil && target.Host == "" {
+		target, err = url.Parse(scraper.Einthusan.BaseURL() + "/" + strings.TrimPrefix(track.URL, "/"))
+	}
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || !allowedSubtitleHost(target.Hostname()) {
+		respondError(c, http.StatusBadGateway, "upstream_error", "subtitle link is not on an Einthusan host")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), subtitleFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, "upstream_error", err.Error())
+		return
+	}
+	setBrowserHeaders(req)
+	res, err := scheduler.do(httpClient, req)
+	if err != nil {
+		subtitleFetchError(c, err)
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body := errorBody("upstream_error", "upstream returned "+res.Status)
+		body["upstream_status"] = res.StatusCode
+		abortWithError(c, http.StatusBadGateway, body)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxSubtitleBytes+1))
+	if err != nil {
+		subtitleFetchError(c, err)
+		return
+	}
+	if len(data) > maxSubtitleBytes {
+		respondError(c, http.StatusBadGateway, "upstream_error", "subtitle file too large")
+		return
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	current := "srt"
+	if bytes.HasPrefix(data, []byte("WEBVTT")) {
+		current = "vtt"
+	}
+	if format == "" {
+		format = current
+	}
+	switch {
+	case format == current:
+	case format == "vtt":
+		data = srtToVTT(data)
+	default:
+		data = vttToSRT(data)
+	}
+	contentType := "text/vtt; charset=utf-8"
+	if format == "srt" {
+		contentType = "application/x-subrip; charset=utf-8"
+	}
+	c.Header("Cache-Control", subtitleCacheControl)
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", path.Base(id)+"."+track.Language+"."+format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// pickSubtitle returns the track for lang, matched case-insensitively
+// against its language or that language's prefix ("en" matches "en-US"),
+// or the first track when lang is empty.
+func pickSubtitle(tracks []models.SubtitleTrack, lang string) (models.SubtitleTrack, bool) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	for _, track := range tracks {
+		trackLang := strings.ToLower(track.Language)
+		if lang == "" || trackLang == lang || strings.HasPrefix(trackLang, lang+"-") {
+			return track, true
+		}
+	}
+	return models.SubtitleTrack{}, false
+}
+
+// allowedSubtitleHost accepts Einthusan's CDN and the configured mirrors.
+func allowedSubtitleHost(host string) bool {
+	if allowedImageHost(host) {
+		return true
+	}
+	for _, mirror := range scraper.Einthusan.URLs {
+		if u, err := url.Parse(mirror); err == nil && strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// srtToVTT adds the WEBVTT header and switches the timestamps' decimal
+// commas to points. Cue numbers are valid VTT cue identifiers and are kept.
+func srtToVTT(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if m := srtTiming.FindStringSubmatch(line); m != nil {
+			lines[i] = strings.ReplaceAll(m[1], ",", ".") + " --> " + strings.ReplaceAll(m[2], ",", ".")
+		}
+	}
+	return []byte("WEBVTT\n\n" + strings.TrimLeft(strings.Join(lines, "\n"), "\n"))
+}
+
+// vttToSRT keeps only the cues of a VTT file, numbered from 1, with full
+// comma-decimal timestamps and without cue settings. The header, NOTE,
+// STYLE and REGION blocks are dropped.
+func vttToSRT(data []byte) []byte {
+	var out strings.Builder
+	n := 0
+	for _, block := range strings.Split(string(data), "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if vttTiming.MatchString(line) {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+		m := vttTiming.FindStringSubmatch(lines[timing])
+		n++
+		fmt.Fprintf(&out, "%d\n%s --> %s\n", n, srtTimestamp(m[1]), srtTimestamp(m[2]))
+		for _, line := range lines[timing+1:] {
+			out.WriteString(line + "\n")
+		}
+		out.WriteString("\n")
+	}
+	return []byte(out.String())
+}
+
+// srtTimestamp turns a VTT timestamp such as "01:02.500" into "00:01:02,500".
+func srtTimestamp(ts string) string {
+	if strings.Count(ts, ":") == 1 {
+		ts = "00:" + ts
+	}
+	return strings.Replace(ts, ".", ",", 1)
+}
+
+func subtitleFetchError(c *gin.Context, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		respondError(c, http.StatusGatewayTimeout, "upstream_timeout", "subtitle fetch timed out")
+		return
+	}
+	if errors.Is(err, errUpstreamBusy) {
+		respondError(c, http.StatusServiceUnavailable, "upstream_busy", "too many upstream requests queued, retry later")
+		return
+	}
+	slog.Warn("subtitle fetch failed", "request_id", requestID(c.Request.Context()), "error", err)
+	respondError(c, http.StatusBadGateway, "upstream_error", "subtitle fetch failed")
+}
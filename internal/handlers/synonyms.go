@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// synonymGroups maps each normalized spelling to its whole group, canonical
+// form first. It stays empty, and search unchanged, unless SYNONYMS_FILE is set.
+var synonymGroups = map[string][]string{}
+
+// LoadSynonyms reads a JSON object of canonical titles to alternate
+// spellings, e.g. {"kaithi": ["kaidhi"], "vikram": ["vickram"]}.
+func LoadSynonyms(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading SYNONYMS_FILE: %w", err)
+	}
+	var aliases map[string][]string
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return fmt.Errorf("parsing SYNONYMS_FILE %s: %w", path, err)
+	}
+	for canonical, spellings := range aliases {
+		group := []string{normalizeTitle(canonical)}
+		for _, spelling := range spellings {
+			group = appendMissing(group, normalizeTitle(spelling))
+		}
+		for _, spelling := range group {
+			synonymGroups[spelling] = group
+		}
+	}
+	return nil
+}
+
+// queryVariants returns the canonical form of query and the other spellings
+// worth searching for. With no matching group it returns query unchanged and
+// no variants.
+func queryVariants(query string) (string, []string) {
+	normalized := normalizeTitle(query)
+	group, ok := synonymGroups[normalized]
+	if !ok {
+		return query, nil
+	}
+	var variants []string
+	for _, spelling := range group {
+		if spelling != normalized {
+			variants = append(variants, spelling)
+		}
+	}
+	return group[0], variants
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Einthusan lists titles in Latin script, but people spell the same title
+// many ways: "Theri" and "Thiri", "Kaththi" and "Kathi", "Zindagi" and
+// "Jindagi", or type it in Tamil, Telugu or Devanagari. foldTitle maps all
+// of those to one key, so ranking compares what a title sounds like rather
+// than how it was romanized. Searches rank by these keys unless they ask for
+// strict=true.
+
+// indicConsonants are the consonants of the scripts romanize reads, without
+// their inherent "a".
+var indicConsonants = map[rune]string{
+	// Devanagari
+	'क': "k", 'ख': "kh", 'ग': "g", 'घ': "gh", 'ङ': "ng",
+	'च': "ch", 'छ': "chh", 'ज': "j", 'झ': "jh", 'ञ': "ny",
+	'ट': "t", 'ठ': "th", 'ड': "d", 'ढ': "dh", 'ण': "n",
+	'त': "t", 'थ': "th", 'द': "d", 'ध': "dh", 'न': "n",
+	'प': "p", 'फ': "ph", 'ब': "b", 'भ': "bh", 'म': "m",
+	'य': "y", 'र': "r", 'ल': "l", 'ळ': "l", 'व': "v",
+	'श': "sh", 'ष': "sh", 'स': "s", 'ह': "h",
+	// Tamil
+	'க': "k", 'ங': "ng", 'ச': "ch", 'ஞ': "ny", 'ட': "t",
+	'ண': "n", 'த': "th", 'ந': "n", 'ப': "p", 'ம': "m",
+	'ய': "y", 'ர': "r", 'ல': "l", 'வ': "v", 'ழ': "zh",
+	'ள': "l", 'ற': "r", 'ன': "n", 'ஜ': "j", 'ஷ': "sh",
+	'ஸ': "s", 'ஹ': "h",
+	// Telugu
+	'క': "k", 'ఖ': "kh", 'గ': "g", 'ఘ': "gh", 'ఙ': "ng",
+	'చ': "ch", 'ఛ': "chh", 'జ': "j", 'ఝ': "jh", 'ఞ': "ny",
+	'ట': "t", 'ఠ': "th", 'డ': "d", 'ఢ': "dh", 'ణ': "n",
+	'త': "t", 'థ': "th", 'ద': "d", 'ధ': "dh", 'న': "n",
+	'ప': "p", 'ఫ': "ph", 'బ': "b", 'భ': "bh", 'మ': "m",
+	'య': "y", 'ర': "r", 'ఱ': "r", 'ల': "l", 'ళ': "l",
+	'వ': "v", 'శ': "sh", 'ష': "sh", 'స': "s", 'హ': "h",
+}
+
+// indicVowelSigns replace a consonant's inherent "a".
+var indicVowelSigns = map[rune]string{
+	// Devanagari
+	'ा': "aa", 'ि': "i", 'ी': "ii", 'ु': "u", 'ू': "uu", 'ृ': "ri",
+	'े': "e", 'ै': "ai", 'ो': "o", 'ौ': "au",
+	// Tamil
+	'ா': "aa", 'ி': "i", 'ீ': "ii", 'ு': "u", 'ூ': "uu",
+	'ெ': "e", 'ே': "ee", 'ை': "ai", 'ொ': "o", 'ோ': "oo", 'ௌ': "au",
+	// Telugu
+	'ా': "aa", 'ి': "i", 'ీ': "ii", 'ు': "u", 'ూ': "uu", 'ృ': "ru",
+	'ె': "e", 'ే': "ee", 'ై': "ai", 'ొ': "o", 'ో': "oo", 'ౌ': "au",
+}
+
+// indicLetters are the independent vowels and the nasal and aspiration
+// marks, which stand on their own.
+var indicLetters = map[rune]string{
+	// Devanagari
+	'अ': "a", 'आ': "aa", 'इ': "i", 'ई': "ii", 'उ': "u", 'ऊ': "uu", 'ऋ': "ri",
+	'ए': "e", 'ऐ': "ai", 'ओ': "o", 'औ': "au", 'ं': "n", 'ँ': "n", 'ः': "h",
+	// Tamil
+	'அ': "a", 'ஆ': "aa", 'இ': "i", 'ஈ': "ii", 'உ': "u", 'ஊ': "uu",
+	'எ': "e", 'ஏ': "ee", 'ஐ': "ai", 'ஒ': "o", 'ஓ': "oo", 'ஔ': "au", 'ஃ': "h",
+	// Telugu
+	'అ': "a", 'ఆ': "aa", 'ఇ': "i", 'ఈ': "ii", 'ఉ': "u", 'ఊ': "uu", 'ఋ': "ru",
+	'ఎ': "e", 'ఏ': "ee", 'ఐ': "ai", 'ఒ': "o", 'ఓ': "oo", 'ఔ': "au", 'ం': "m", 'ః': "h",
+}
+
+// indicViramas drop the inherent vowel, joining a consonant to the next.
+var indicViramas = map[rune]bool{'्': true, '்': true, '్': true}
+
+// romanize spells Devanagari, Tamil and Telugu text in Latin letters the way
+// titles are usually romanized, leaving everything else as it is. Hindi
+// drops a word's final inherent vowel ("राम" is "ram", not "rama").
+func romanize(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return unicode.In(r, unicode.Devanagari, unicode.Tamil, unicode.Telugu) }) {
+		return s
+	}
+	var b strings.Builder
+	pending, hindi := false, false // a consonant still owes its inherent vowel
+	for _, r := range norm.NFC.String(s) {
+		if c, ok := indicConsonants[r]; ok {
+			if pending {
+				b.WriteByte('a')
+			}
+			b.WriteString(c)
+			pending, hindi = true, unicode.Is(unicode.Devanagari, r)
+			continue
+		}
+		if v, ok := indicVowelSigns[r]; ok {
+			b.WriteString(v)
+			pending = false
+			continue
+		}
+		if indicViramas[r] {
+			pending = false
+			continue
+		}
+		if r == '़' || r == '‌' || r == '‍' { // nukta, zero-width (non-)joiner
+			continue
+		}
+		if pending && (!hindi || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Devanagari)) {
+			b.WriteByte('a')
+		}
+		pending = false
+		if v, ok := indicLetters[r]; ok {
+			b.WriteString(v)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	if pending && !hindi {
+		b.WriteByte('a')
+	}
+	return b.String()
+}
+
+// stripMarks removes accents and folds compatibility forms, so "Café" and
+// full-width "Ｃａｆｅ" both become "Cafe".
+var stripMarks = transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// phoneticDigraphs fold the spellings romanizations disagree on: aspirated
+// consonants written with or without the h, Tamil's zh written as l, and
+// letters that stand in for one another.
+var phoneticDigraphs = strings.NewReplacer(
+	"zh", "l", "th", "t", "dh", "d", "kh", "k", "gh", "g", "ch", "c",
+	"jh", "j", "sh", "s", "bh", "b", "ph", "f", "ck", "k",
+	"w", "v", "z", "j", "q", "k", "x", "ks",
+)
+
+// phoneticLetters then merges vowels and consonants that are written
+// interchangeably: e and i, o and u, and the voiced and unvoiced stops Tamil
+// script doesn't tell apart ("Kadhal" and "Kaathal").
+var phoneticLetters = strings.NewReplacer("e", "i", "o", "u", "d", "t", "g", "k", "b", "p")
+
+// foldTitle returns title's transliteration key: romanized, stripped of
+// accents, normalized as normalizeTitle does, then folded phonetically with
+// doubled letters collapsed, so long vowels and geminates match single ones.
+// Digits and the spaces between words are kept.
+func foldTitle(title string) string {
+	plain, _, err := transform.String(stripMarks, romanize(title))
+	if err != nil {
+		plain = title
+	}
+	words := strings.Fields(normalizeTitle(plain))
+	for i, word := range words {
+		word = phoneticLetters.Replace(phoneticDigraphs.Replace(word))
+		var b strings.Builder
+		var last rune
+		for _, r := range word {
+			if r != last {
+				b.WriteRune(r)
+			}
+			last = r
+		}
+		words[i] = b.String()
+	}
+	return strings.Join(words, " ")
+}
+
+// wantsStrict reads strict=, which ranks search results by their titles as
+// written instead of by foldTitle's keys. It responds 400 itself when the
+// value isn't a boolean.
+func wantsStrict(c *gin.Context) (bool, bool) {
+	raw := c.Query("strict")
+	if raw == "" {
+		return false, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "strict must be true or false")
+		return false, false
+	}
+	return v, true
+}
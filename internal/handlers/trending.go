@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// TrendingResponse is the first page of the popular and recent listings,
+// plus both merged with popular first and duplicates dropped.
+type TrendingResponse struct {
+	Language string              `json:"language"`
+	Popular  []models.MovieEntry `json:"popular"`
+	Recent   []models.MovieEntry `json:"recent"`
+	All      []models.MovieEntry `json:"all"`
+}
+
+// popularWindows maps browse's window= values to Einthusan's tp parameter,
+// the period views are counted over for the popular listing.
+var popularWindows = map[string]string{"week": "l7d", "month": "l30d", "year": "l1y", "alltime": "alltime"}
+
+// popularWindowNames are popularWindows' keys, shortest period first.
+var popularWindowNames = []string{"week", "month", "year", "alltime"}
+
+// browseUrl builds the upstream listing URL for a browse category, reporting
+// false for categories Einthusan doesn't have. Popular is all-time.
+func browseUrl(language, category string) (string, bool) {
+	switch category {
+	case "popular":
+		return popularUrl(language, "alltime"), true
+	case "recent":
+		return fmt.Sprintf("%s/movie/results/?find=Recent&lang=%s", scraper.Einthusan.BaseURL(), language), true
+	}
+	return "", false
+}
+
+// popularUrl builds the popular listing's URL for one of popularWindows.
+func popularUrl(language, window string) string {
+	return fmt.Sprintf("%s/movie/results/?find=Popularity&lang=%s&ptype=view&tp=%s", scraper.Einthusan.BaseURL(), language, popularWindows[window])
+}
+
+// trending fetches the popular and recent first pages concurrently, saving
+// clients two requests and a client-side merge. provider= picks the source,
+// which must have both listings.
+func trending(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	source, ok := requireProvider(c, language)
+	if !ok {
+		return
+	}
+	categories := []string{"popular", "recent"}
+	if supported := source.info().Categories; !slices.Contains(supported, "popular") || !slices.Contains(supported, "recent") {
+		body := errorBody("unsupported_provider", source.info().Name+" has no popular and recent listings")
+		body["supported"] = []string{defaultProvider}
+		abortWithError(c, http.StatusBadRequest, body)
+		return
+	}
+	results := make([]scraper.Listing, len(categories))
+	errs := make([]error, len(categories))
+	var wg sync.WaitGroup
+	for i, category := range categories {
+		wg.Go(func() {
+			results[i], errs[i] = source.browse(c.Request.Context(), language, category, "", 1)
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			respondScrapeError(c, err)
+			return
+		}
+	}
+
+	popular := append([]models.MovieEntry{}, results[0].Movies...)
+	recent := append([]models.MovieEntry{}, results[1].Movies...)
+	respond(c, http.StatusOK, TrendingResponse{
+		Language: language,
+		Popular:  popular,
+		Recent:   recent,
+		All:      mergeMovies(append([]models.MovieEntry{}, popular...), recent),
+	})
+}
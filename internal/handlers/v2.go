@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/models"
+)
+
+// ListPage is the one envelope every /v2 listing answers with, in place of
+// the v1 routes' three differently shaped responses.
+type ListPage struct {
+	Items      []models.MovieEntry `json:"items"`
+	Page       int                 `json:"page"`
+	NextCursor string              `json:"next_cursor,omitempty"` // Pass back as cursor= for the next page; omitted on the last
+	HasMore    bool                `json:"has_more"`
+	Source     string              `json:"source"` // "cache", "upstream", or "stale" when an expired entry covered for a failed scrape
+	Count      int                 `json:"count"`
+
+	TotalResults   int    `json:"total_results,omitempty"`   // 0 when the upstream doesn't say
+	Reason         string `json:"reason,omitempty"`          // Why Items is empty
+	ScrapeDegraded bool   `json:"scrape_degraded,omitempty"` // See SearchResponse
+}
+
+// listCursor is what a next_cursor stands for: which listing, and the page
+// to continue from. Clients treat it as opaque; it is base64url JSON so it
+// survives a query string unescaped.
+type listCursor struct {
+	Kind     string `json:"k"` // "browse", "search" or "actor"
+	Language string `json:"l"`
+	Category string `json:"c,omitempty"`
+	Window   string `json:"w,omitempty"`
+	Query    string `json:"q,omitempty"`
+	Actor    string `json:"a,omitempty"`
+	Page     int    `json:"p"`
+}
+
+func (lc listCursor) encode() string {
+	data, _ := json.Marshal(lc)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// readCursor returns the listing a /v2 request asks for: the one its
+// cursor= names, or else start at its page= parameter. A cursor must belong
+// to the route and language it is sent to; anything else is answered 400.
+func readCursor(c *gin.Context, start listCursor) (listCursor, bool) {
+	raw := c.Query("cursor")
+	if raw == "" {
+		start.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+		return start, true
+	}
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.Page < 1 {
+		respondError(c, http.StatusBadRequest, "invalid_cursor", "cursor is malformed")
+		return listCursor{}, false
+	}
+	if cursor.Kind != start.Kind || cursor.Language != start.Language {
+		respondError(c, http.StatusBadRequest, "invalid_cursor", "cursor belongs to a different listing")
+		return listCursor{}, false
+	}
+	return cursor, true
+}
+
+// respondPage fills in page's cursor, source and count and sends it. cursor
+// is the listing just served; nextPage is 0 at its end.
+func respondPage(c *gin.Context, cursor listCursor, nextPage int, page ListPage) {
+	if page.Items == nil {
+		page.Items = []models.MovieEntry{}
+	}
+	if nextPage > 0 {
+		cursor.Page = nextPage
+		page.NextCursor = cursor.encode()
+	}
+	switch scopeFrom(c.Request.Context()).cacheStatus() {
+	case cacheStatusNames[cacheHit]:
+		page.Source = "cache"
+	case cacheStatusNames[cacheStale]:
+		page.Source = "stale"
+	default:
+		page.Source = "upstream"
+	}
+	page.Count = len(page.Items)
+	respond(c, http.StatusOK, page)
+}
+
+// browseV2 is browse in the ListPage envelope.
+func browseV2(c *gin.Context) {
+	language, ok := browseLanguage(c)
+	if !ok {
+		return
+	}
+	cursor, ok := readCursor(c, listCursor{Kind: "browse", Language: language, Category: strings.ToLower(c.DefaultQuery("category", "recent")), Window: strings.ToLower(c.Query("window"))})
+	if !ok {
+		return
+	}
+	resp, ok := browseListing(c, language, cursor.Category, cursor.Window, cursor.Page)
+	if !ok {
+		return
+	}
+	respondPage(c, cursor, resp.NextPage, ListPage{Items: resp.Movies, Page: resp.Page, HasMore: resp.HasMore, TotalResults: resp.TotalResults, Reason: resp.Reason, ScrapeDegraded: resp.ScrapeDegraded})
+}
+
+// searchV2 is searchMovies in the ListPage envelope.
+func searchV2(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	cursor, ok := readCursor(c, listCursor{Kind: "search", Language: language, Query: strings.Join(strings.Fields(c.Query("q")), " ")})
+	if !ok {
+		return
+	}
+	resp, ok := searchResults(c, language, cursor.Query, cursor.Page)
+	if !ok {
+		return
+	}
+	respondPage(c, cursor, resp.NextPage, ListPage{Items: resp.Movies, Page: resp.Page, HasMore: resp.HasMore, TotalResults: resp.EstimatedTotal, Reason: resp.Reason, ScrapeDegraded: resp.ScrapeDegraded})
+}
+
+// actorV2 is actorFilmography in the ListPage envelope. The cursor also
+// carries the actor code, so it can't be replayed against another actor.
+func actorV2(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	actorCode := c.Param("actorcode")
+	cursor, ok := readCursor(c, listCursor{Kind: "actor", Language: language, Actor: actorCode})
+	if !ok {
+		return
+	}
+	if cursor.Actor != actorCode {
+		respondError(c, http.StatusBadRequest, "invalid_cursor", "cursor belongs to a different listing")
+		return
+	}
+	resp, ok := actorListing(c, language, actorCode, cursor.Page)
+	if !ok {
+		return
+	}
+	respondPage(c, cursor, resp.NextPage, ListPage{Items: resp.Movies, Page: resp.Page, HasMore: resp.HasMore, TotalResults: resp.TotalResults, Reason: resp.Reason, ScrapeDegraded: resp.ScrapeDegraded})
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gin-gonic/gin"
+
+	"apppp/internal/scraper"
+)
+
+type WatchResponse struct {
+	Title    string `json:"title"`
+	VideoUrl string `json:"video_url"`
+	ImgUrl   string `json:"img_url"`
+}
+
+// StreamSource is one playable rendition of a movie.
+type StreamSource struct {
+	URL     string `json:"url"`
+	Type    string `json:"type"`    // "mp4" or "hls"
+	Quality string `json:"quality"` // "hd", "sd", or "auto" for adaptive HLS
+}
+
+type StreamResponse struct {
+	ID       string         `json:"id"`
+	Language string         `json:"language"`
+	Sources  []StreamSource `json:"sources"`
+}
+
+// watchMovie resolves the stream link for a movie named by ?language= and
+// ?id=, or by a watch page ?url=. Either way the page is fetched from the
+// canonical URL for the ID, so a link from an old domain keeps working. The
+// JSON form is written without HTML escaping so the link's query string
+// comes through as is.
+func watchMovie(c *gin.Context) {
+	input := cmp.Or(c.Query("url"), c.Query("id"))
+	if input == "" {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", "url or id is required")
+		return
+	}
+	language, id, err := movieTarget(input, strings.ToLower(strings.TrimSpace(c.Query("language"))))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+	watchData, err := scrapeWatchDetails(c.Request.Context(), watchUrl(language, id))
+	if err != nil {
+		respondScrapeError(c, err)
+		return
+	}
+	if wantsMsgPack(c.GetHeader("Accept")) {
+		respond(c, http.StatusOK, watchData)
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	encoder := json.NewEncoder(c.Writer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(watchData); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// scrapeWatchDetails reads a watch page's title, poster and video link. It
+// works in every build; without the "stream" build tag only plain player
+// links are found, and video_url is empty for a page that obfuscates its link.
+func scrapeWatchDetails(ctx context.Context, url string) (*WatchResponse, error) {
+	res, err := fetchUpstream(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlocked(res, doc); err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(doc.Find("#UIMovieSummary div.block2 a.title h3").First().Text())
+
+	imgSrc, _ := doc.Find("#UIMovieSummary div.block1 img").Attr("src")
+	if strings.HasPrefix(imgSrc, "//") {
+		imgSrc = "https:" + imgSrc
+	}
+
+	// A page without a usable link still yields its title and poster.
+	finalUrl, _ := extractStreamUrl(doc)
+
+	return &WatchResponse{
+		Title:    title,
+		VideoUrl: finalUrl,
+		ImgUrl:   imgSrc,
+	}, nil
+}
+
+// extractStreamUrl returns the MP4 (preferred) or HLS link from a watch page's
+// video player. Plain data-mp4-link/data-hls-link attributes are read in
+// every build; decoding the obfuscated player payload needs the "stream"
+// build tag, and without it that case returns errStreamingDisabled.
+func extractStreamUrl(doc *goquery.Document) (string, error) {
+	player := doc.Find("#UIVideoPlayer").First()
+	if player.Length() == 0 {
+		return "", errors.New("page has no video player")
+	}
+	for _, attr := range []string{"data-mp4-link", "data-hls-link"} {
+		if link, _ := player.Attr(attr); link != "" {
+			return normalizeStreamUrl(link), nil
+		}
+	}
+	return decodePlayerPayload(player)
+}
+
+// rawIPHost matches the bare IP hosts Einthusan sometimes puts in video
+// links; they are swapped for the CDN hostname so TLS validates.
+var rawIPHost = regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)
+
+func normalizeStreamUrl(link string) string {
+	if strings.HasPrefix(link, "//") {
+		link = "https:" + link
+	}
+	return rawIPHost.ReplaceAllString(link, "cdn1.einthusan.io")
+}
+
+// streamLinks lists every playable rendition of a movie.
+func streamLinks(c *gin.Context) {
+	language, ok := requireLanguage(c)
+	if !ok {
+		return
+	}
+	streams, err := resolveStreams(c.Request.Context(), language, c.Param("movieid"))
+	switch {
+	case errors.Is(err, errStreamingDisabled):
+		respondError(c, http.StatusNotImplemented, "streaming_disabled", err.Error())
+	case errors.Is(err, scraper.ErrNotFound):
+		respondError(c, http.StatusNotFound, "movie_not_found", "movie not found")
+	case err != nil:
+		respondScrapeError(c, err)
+	default:
+		respond(c, http.StatusOK, streams)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"apppp/internal/models"
+)
+
+// watchPageWith is a minimal watch page whose player carries the given
+// attributes.
+func watchPageWith(player string) string {
+	return `<html><body>
+<div id="UIMovieSummary"><div class="block1"><img src="//img.einthusan.io/tamil/A00x.jpg"></div>
+<div class="block2"><a class="title"><h3>Theri</h3></a></div></div>
+<div id="UIVideoPlayer" ` + player + `></div>
+</body></html>`
+}
+
+func TestWatchReadsPlainPlayerLink(t *testing.T) {
+	fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(watchPageWith(`data-mp4-link="//1.2.3.4/tamil/A00x.mp4?e=1&s=2"`)))
+	})
+	w := httptest.NewRecorder()
+	NewRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watch?language=tamil&id=A00x", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body %s", w.Code, w.Body)
+	}
+	var got WatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := WatchResponse{Title: "Theri", VideoUrl: "https://cdn1.einthusan.io/tamil/A00x.mp4?e=1&s=2", ImgUrl: "https://img.einthusan.io/tamil/A00x.jpg"}
+	if got != want {
+		t.Errorf("watch = %+v, want %+v", got, want)
+	}
+}
+
+func TestMovieOmitsUndecodableStreamQuietly(t *testing.T) {
+	if streamingEnabled {
+		t.Skip("this build decodes the player payload")
+	}
+	fakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(watchPageWith(`data-ejpingables="obfuscated"`)))
+	})
+	w := httptest.NewRecorder()
+	NewRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/movie/tamil/A00x", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body %s", w.Code, w.Body)
+	}
+	var got models.MovieDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.StreamUrl != "" || len(got.Warnings) != 0 {
+		t.Errorf("stream_url = %q, warnings = %q; want neither without the stream tag", got.StreamUrl, got.Warnings)
+	}
+}
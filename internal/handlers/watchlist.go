@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+
+	"apppp/internal/models"
+	"apppp/internal/scraper"
+)
+
+// watchlistBucket holds one JSON WatchlistEntry per movie ID.
+var watchlistBucket = []byte("watchlist")
+
+// watchlistDB is the saved-movies store, opened by OpenWatchlist when
+// WATCHLIST_DB names a file. Without it the watchlist routes answer 501.
+var watchlistDB *bolt.DB
+
+type WatchlistEntry struct {
+	models.MovieEntry
+	Language string    `json:"language,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+type WatchlistResponse struct {
+	Movies []WatchlistEntry `json:"movies"`
+	Count  int              `json:"count"`
+}
+
+func OpenWatchlist(path string) error {
+	if path == "" {
+		return nil
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("watchlist: open %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(watchlistBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return fmt.Errorf("watchlist: init %s: %w", path, err)
+	}
+	watchlistDB = db
+	return nil
+}
+
+// requireWatchlist answers 501 on every watchlist route when no database is configured.
+func requireWatchlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if watchlistDB == nil {
+			respondError(c, http.StatusNotImplemented, "watchlist_disabled", "watchlist is not enabled (set WATCHLIST_DB)")
+			return
+		}
+		c.Next()
+	}
+}
+
+// addToWatchlist saves the posted movie. The ID may be omitted when page_url
+// is a watch link. Saving a movie again updates its fields but keeps the
+// original added_at.
+func addToWatchlist(c *gin.Context) {
+	var entry WatchlistEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_body", "body must be a JSON movie entry")
+		return
+	}
+	if entry.ID == "" {
+		entry.ID = scraper.MovieID(entry.PageUrl)
+	}
+	if entry.ID == "" {
+		respondError(c, http.StatusUnprocessableEntity, "invalid_body", "id or a watch page_url is required")
+		return
+	}
+	entry.Language = strings.ToLower(strings.TrimSpace(entry.Language))
+	if entry.Language != "" && !slices.Contains(knownLanguages(), entry.Language) {
+		respondError(c, http.StatusBadRequest, "unsupported_language", "unsupported language")
+		return
+	}
+
+	status := http.StatusCreated
+	err := watchlistDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(watchlistBucket)
+		entry.AddedAt = time.Now().UTC()
+		if existing := b.Get([]byte(entry.ID)); existing != nil {
+			var old WatchlistEntry
+			if json.Unmarshal(existing, &old) == nil {
+				entry.AddedAt = old.AddedAt
+			}
+			status = http.StatusOK
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(entry.ID), data)
+	})
+	if err != nil {
+		respondInternalError(c, "storage_error", err)
+		return
+	}
+	respond(c, status, entry)
+}
+
+// listWatchlist returns every saved movie, most recently added first.
+func listWatchlist(c *gin.Context) {
+	resp := WatchlistResponse{Movies: []WatchlistEntry{}}
+	err := watchlistDB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(watchlistBucket).ForEach(func(k, v []byte) error {
+			var entry WatchlistEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return fmt.Errorf("entry %s: %w", k, err)
+			}
+			resp.Movies = append(resp.Movies, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		respondInternalError(c, "storage_error", err)
+		return
+	}
+	slices.SortStableFunc(resp.Movies, func(a, b WatchlistEntry) int { return b.AddedAt.Compare(a.AddedAt) })
+	resp.Count = len(resp.Movies)
+	respond(c, http.StatusOK, resp)
+}
+
+var errNotInWatchlist = errors.New("movie is not in the watchlist")
+
+func removeFromWatchlist(c *gin.Context) {
+	id := c.Param("id")
+	err := watchlistDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(watchlistBucket)
+		if b.Get([]byte(id)) == nil {
+			return errNotInWatchlist
+		}
+		return b.Delete([]byte(id))
+	})
+	if errors.Is(err, errNotInWatchlist) {
+		respondError(c, http.StatusNotFound, "not_in_watchlist", err.Error())
+		return
+	}
+	if err != nil {
+		respondInternalError(c, "storage_error", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,118 @@
+// Package models holds the response shapes shared by the scraper and the
+// handlers.
+package models
+
+import "cmp"
+
+// Response shapes shared across the listing endpoints. Types that belong to a
+// single endpoint live next to its handler, in package handlers, instead.
+
+type MovieEntry struct {
+	ID      string `json:"id"` // Einthusan movie ID, as taken by /movie/:language/:id; empty if the link has none
+	ImgUrl  string `json:"img_url"`
+	PageUrl string `json:"page_url"`
+	Title   string `json:"title"`
+	Year    int    `json:"year"` // Release year, 0 when the listing doesn't show one
+
+	// Read from the listing itself, but only returned with fields=full.
+	Duration string `json:"duration,omitempty"` // Runtime as shown, e.g. "2h 38m"
+	Synopsis string `json:"synopsis,omitempty"` // The listing's synopsis excerpt
+	Views    int    `json:"views,omitempty"`    // View count, when the listing shows one
+
+	// Filled in from the movie's own page only when the request asks for enrich=true.
+	Trailer  string `json:"trailer_url,omitempty"`
+	PosterHD string `json:"poster_hd,omitempty"`
+}
+
+// Key identifies the movie across listings. The ID survives a change of
+// mirror or domain where the page URL doesn't, so the URL is only the
+// fallback for links without one.
+func (m MovieEntry) Key() string {
+	return cmp.Or(m.ID, m.PageUrl)
+}
+
+type SearchResponse struct {
+	Language string       `json:"language"`
+	Movies   []MovieEntry `json:"movies"`
+	Query    string       `json:"q"`
+	Page     int          `json:"page"`      // Added for pagination
+	NextPage int          `json:"next_page"` // Added for pagination
+	HasMore  bool         `json:"has_more"`  // Added for pagination
+
+	// EstimatedTotal is the match count Einthusan reports for the query
+	// across all pages; omitted when the results header doesn't show one.
+	EstimatedTotal int    `json:"estimated_total,omitempty"`
+	Reason         string `json:"reason,omitempty"` // Why Movies is empty
+	// ScrapeDegraded means Einthusan listed movies that the selectors
+	// couldn't read, so an empty Movies is our failure, not a real result.
+	ScrapeDegraded bool `json:"scrape_degraded,omitempty"`
+}
+
+type BrowseResponse struct {
+	Category       string       `json:"category"`
+	Window         string       `json:"window,omitempty"` // Period popular counts views over
+	HasMore        bool         `json:"has_more"`
+	Language       string       `json:"language"`
+	Movies         []MovieEntry `json:"movies"`
+	NextPage       int          `json:"next_page"`
+	Page           int          `json:"page"`
+	PageSize       int          `json:"page_size"`                 // Movies on a full upstream page; see count for this response
+	Count          int          `json:"count"`                     // len(movies), for pagination UIs
+	Reason         string       `json:"reason,omitempty"`          // Why Movies is empty
+	TotalPages     int          `json:"total_pages,omitempty"`     // 0 when the upstream doesn't say
+	TotalResults   int          `json:"total_results,omitempty"`   // 0 when the upstream doesn't say
+	ScrapeDegraded bool         `json:"scrape_degraded,omitempty"` // See SearchResponse
+}
+
+type ActorResponse struct {
+	ActorID        string       `json:"actor_id"`
+	ActorName      string       `json:"actor_name"`
+	HasMore        bool         `json:"has_more"`
+	Language       string       `json:"language"`
+	Movies         []MovieEntry `json:"movies"`
+	NextPage       int          `json:"next_page"`
+	Page           int          `json:"page"`
+	PageSize       int          `json:"page_size"`                 // Movies on a full upstream page; see count for this response
+	Count          int          `json:"count"`                     // len(movies), for pagination UIs
+	Reason         string       `json:"reason,omitempty"`          // Why Movies is empty
+	TotalPages     int          `json:"total_pages,omitempty"`     // 0 when the upstream doesn't say
+	TotalResults   int          `json:"total_results,omitempty"`   // 0 when the upstream doesn't say
+	ScrapeDegraded bool         `json:"scrape_degraded,omitempty"` // See SearchResponse
+}
+
+// CastMember is one credited person on a movie page. ID is the code the
+// /actors endpoint takes, empty when the page doesn't link the person.
+type CastMember struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+// SubtitleTrack is one subtitle file offered by the watch page's player.
+type SubtitleTrack struct {
+	Language string `json:"language"`
+	URL      string `json:"url"`
+}
+
+// MovieDetail is the metadata on a single movie page. Fields the page doesn't
+// show are left empty rather than failing the request.
+type MovieDetail struct {
+	ID        string          `json:"id"`
+	Language  string          `json:"language"`
+	Title     string          `json:"title"`
+	ImgUrl    string          `json:"img_url,omitempty"`
+	Synopsis  string          `json:"synopsis,omitempty"`
+	Year      int             `json:"year,omitempty"`
+	Duration  string          `json:"duration,omitempty"`
+	Director  string          `json:"director,omitempty"`
+	Rating    float64         `json:"rating,omitempty"` // Average user rating as shown on the page
+	Genres    []string        `json:"genres"`
+	Trailer   string          `json:"trailer_url,omitempty"` // YouTube link, when the page has one
+	PosterHD  string          `json:"poster_hd,omitempty"`   // Full-size poster, when larger than ImgUrl
+	Cast      []CastMember    `json:"cast"`
+	Subtitles []SubtitleTrack `json:"subtitles"`
+	// StreamUrl is the playable MP4 or HLS link. When it can't be resolved
+	// it is omitted and Warnings says why.
+	StreamUrl string   `json:"stream_url,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
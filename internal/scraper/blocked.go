@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Why the upstream served a block page instead of the one asked for. They
+// are the reason in upstream_blocked errors and upstream_blocked_total.
+const (
+	BlockChallenge = "challenge"     // a JavaScript interstitial, like Cloudflare's "Just a moment"
+	BlockCaptcha   = "captcha"       // a CAPTCHA for a human to solve
+	BlockGeo       = "geo_blocked"   // refused for the country we scrape from
+	BlockDenied    = "access_denied" // our IP or user agent is banned outright
+)
+
+// BlockReasons lists every reason, for metrics to report even at zero.
+var BlockReasons = []string{BlockChallenge, BlockCaptcha, BlockGeo, BlockDenied}
+
+// blockMarkers are the phrases that give each kind of block page away, looked
+// for in a page's <title> and <h1>, and in the whole text of an error
+// response. Block pages are short, while a 200 listing may well mention a
+// country in some synopsis, hence the narrower search there.
+var blockMarkers = []struct {
+	reason  string
+	phrases []string
+}{
+	{BlockChallenge, []string{"just a moment", "checking your browser", "ddos protection"}},
+	{BlockCaptcha, []string{"captcha", "attention required", "are you a robot", "verify you are human", "human verification"}},
+	{BlockGeo, []string{"not available in your country", "not available in your region", "banned the country", "error 1009", "geo-restricted", "geographic restriction"}},
+	{BlockDenied, []string{"access denied", "you have been blocked", "error 1020", "error 1006", "forbidden"}},
+	{BlockChallenge, []string{"cloudflare"}}, // any other Cloudflare page
+}
+
+// captchaWidgets match the embeds of the common CAPTCHA services.
+const captchaWidgets = `.g-recaptcha, .h-captcha, .cf-turnstile, iframe[src*="recaptcha"], iframe[src*="hcaptcha"]`
+
+// DetectBlock returns a *BlockedError when res is a bot challenge, CAPTCHA,
+// geo-block or ban page rather than an Einthusan page.
+func DetectBlock(res *http.Response, doc *goquery.Document) error {
+	if reason := blockReason(res, doc); reason != "" {
+		return &BlockedError{Reason: reason}
+	}
+	return nil
+}
+
+func blockReason(res *http.Response, doc *goquery.Document) string {
+	switch {
+	case res.StatusCode == http.StatusUnavailableForLegalReasons:
+		return BlockGeo
+	case res.Header.Get("Cf-Mitigated") == "challenge":
+		return BlockChallenge
+	case doc.Find("#challenge-form, #cf-challenge-running, .cf-browser-verification").Length() > 0:
+		return BlockChallenge
+	case doc.Find(captchaWidgets).Length() > 0:
+		return BlockCaptcha
+	}
+	text := doc.Find("title").First().Text() + "\n" + doc.Find("h1").First().Text()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		text = doc.Text()
+	}
+	text = strings.ToLower(text)
+	for _, marker := range blockMarkers {
+		for _, phrase := range marker.phrases {
+			if strings.Contains(text, phrase) {
+				return marker.reason
+			}
+		}
+	}
+	return ""
+}
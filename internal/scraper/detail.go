@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"apppp/internal/models"
+)
+
+// ParseMovieDetail reads a movie's metadata from its watch page without
+// touching the network. It reports false when the page has no movie summary
+// block. The stream link is left to the caller.
+func ParseMovieDetail(doc *goquery.Document, language, id string) (*models.MovieDetail, bool) {
+	summary := doc.Find(Selectors.Container).First()
+	if summary.Length() == 0 {
+		return nil, false
+	}
+
+	detail := &models.MovieDetail{ID: id, Language: language, Genres: []string{}, Cast: []models.CastMember{}, Subtitles: parseSubtitles(doc)}
+	detail.Title, detail.Year = splitTitleYear(strings.TrimSpace(summary.Find("div.block2 a.title h3").First().Text()))
+	detail.ImgUrl, _ = summary.Find("div.block1 img").Attr("src")
+	if strings.HasPrefix(detail.ImgUrl, "//") {
+		detail.ImgUrl = "https:" + detail.ImgUrl
+	}
+	detail.Synopsis = FirstText(summary, Selectors.Synopsis)
+	detail.Trailer = parseTrailer(doc)
+	detail.PosterHD = parsePosterHD(doc, detail.ImgUrl)
+
+	info := FirstText(summary, Selectors.Info)
+	if year := yearPattern.FindString(info); year != "" {
+		detail.Year, _ = strconv.Atoi(year)
+	}
+	detail.Duration = durationPattern.FindString(info)
+
+	rating := summary.Find(".average-rating, .rating").First()
+	raw, ok := rating.Attr("data-value")
+	if !ok {
+		raw = rating.Text()
+	}
+	if match := ratingPattern.FindString(raw); match != "" {
+		detail.Rating, _ = strconv.ParseFloat(match, 64)
+	}
+	summary.Find(`a[href*="genre="]`).Each(func(i int, s *goquery.Selection) {
+		if genre := strings.TrimSpace(s.Text()); genre != "" && !slices.Contains(detail.Genres, genre) {
+			detail.Genres = append(detail.Genres, genre)
+		}
+	})
+
+	summary.Find("div.professionals div.prof").Each(func(i int, s *goquery.Selection) {
+		name := strings.TrimSpace(s.Find("p").First().Text())
+		if name == "" {
+			return
+		}
+		role := strings.TrimSpace(s.Find("label").First().Text())
+		if strings.EqualFold(role, "director") {
+			if detail.Director == "" {
+				detail.Director = name
+			}
+			return
+		}
+		member := models.CastMember{Name: name, Role: role}
+		if href, ok := s.Find(`a[href*="find=Cast"]`).Attr("href"); ok {
+			if u, err := url.Parse(href); err == nil {
+				member.ID = u.Query().Get("id")
+			}
+		}
+		detail.Cast = append(detail.Cast, member)
+	})
+	return detail, true
+}
+
+// parseSubtitles collects subtitle tracks from <track> elements and from the
+// player's data-subtitle attribute. Tracks without a language are Einthusan's
+// own English subtitles, and site-relative links are made absolute. It
+// returns an empty slice, never nil.
+func parseSubtitles(doc *goquery.Document) []models.SubtitleTrack {
+	tracks := []models.SubtitleTrack{}
+	seen := make(map[string]bool)
+	add := func(language, src string) {
+		src = strings.TrimSpace(src)
+		if strings.HasPrefix(src, "//") {
+			src = "https:" + src
+		} else if strings.HasPrefix(src, "/") {
+			src = Einthusan.BaseURL() + src
+		}
+		if src == "" || seen[src] {
+			return
+		}
+		seen[src] = true
+		if language == "" {
+			language = "en"
+		}
+		tracks = append(tracks, models.SubtitleTrack{Language: language, URL: src})
+	}
+
+	doc.Find("track").Each(func(i int, s *goquery.Selection) {
+		kind, _ := s.Attr("kind")
+		if kind != "" && kind != "subtitles" && kind != "captions" {
+			return
+		}
+		language, _ := s.Attr("srclang")
+		if language == "" {
+			language, _ = s.Attr("label")
+		}
+		src, _ := s.Attr("src")
+		add(strings.TrimSpace(language), src)
+	})
+	if src, ok := doc.Find("#UIVideoPlayer").Attr("data-subtitle"); ok {
+		add("", src)
+	}
+	return tracks
+}
+
+var YoutubeIDPattern = regexp.MustCompile(`(?:v=|youtu\.be/|/embed/)([\w-]{11})`)
+
+// parseTrailer returns the page's YouTube trailer as a canonical watch URL,
+// or "" when there is none.
+func parseTrailer(doc *goquery.Document) string {
+	for _, selector := range Selectors.Trailer {
+		s := doc.Find(selector).First()
+		link, ok := s.Attr("href")
+		if !ok {
+			link, _ = s.Attr("src")
+		}
+		if match := YoutubeIDPattern.FindStringSubmatch(link); match != nil {
+			return "https://www.youtube.com/watch?v=" + match[1]
+		}
+	}
+	return ""
+}
+
+// parsePosterHD returns the full-size poster the page advertises for
+// sharing, or "" when it is missing or the same as the listing image.
+func parsePosterHD(doc *goquery.Document, thumbnail string) string {
+	poster, _ := doc.Find(`meta[property="og:image"]`).Attr("content")
+	if strings.HasPrefix(poster, "//") {
+		poster = "https:" + poster
+	}
+	if poster == thumbnail {
+		return ""
+	}
+	return poster
+}
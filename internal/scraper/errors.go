@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrUpstreamBlocked means Einthusan or its CDN answered with a block page
+// (see BlockedError) instead of the page; ErrUnexpectedPage means it answered
+// with something that isn't a results page at all. Both are outages, not
+// empty results.
+var (
+	ErrUpstreamBlocked = errors.New("upstream blocked the request")
+	ErrUnexpectedPage  = errors.New("upstream returned an unexpected page")
+)
+
+// BlockedError is a block page, with one of BlockReasons. It counts as
+// ErrUpstreamBlocked.
+type BlockedError struct {
+	Reason string
+}
+
+var blockMessages = map[string]string{
+	BlockChallenge: "upstream returned a bot challenge",
+	BlockCaptcha:   "upstream asked for a CAPTCHA",
+	BlockGeo:       "upstream is not available from this server's region",
+	BlockDenied:    "upstream denied access to this server",
+}
+
+func (e *BlockedError) Error() string {
+	return blockMessages[e.Reason]
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrUpstreamBlocked
+}
+
+// ErrParseFailed means an upstream response had the expected shape but its
+// contents couldn't be decoded.
+var ErrParseFailed = errors.New("upstream response could not be parsed")
+
+// UpstreamStatusError is an upstream answer whose HTTP status we can't use.
+// It counts as ErrUnexpectedPage, and error responses pass the status on in
+// upstream_status.
+type UpstreamStatusError struct {
+	source string // what answered, e.g. "profile page"
+	Status int
+	text   string // res.Status
+}
+
+func (e *UpstreamStatusError) Error() string {
+	return fmt.Sprintf("%v: %s returned %s", ErrUnexpectedPage, e.source, e.text)
+}
+
+func (e *UpstreamStatusError) Is(target error) bool {
+	return target == ErrUnexpectedPage
+}
+
+// UnexpectedStatus reports res, answered by source, as an unusable status.
+func UnexpectedStatus(source string, res *http.Response) error {
+	return &UpstreamStatusError{source: source, Status: res.StatusCode, text: res.Status}
+}
+
+// ErrNotFound reports that the upstream has no page for the requested ID.
+var ErrNotFound = errors.New("not found")
@@ -0,0 +1,97 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// defaultBaseUrl is used when EINTHUSAN_BASE_URL is unset.
+const defaultBaseUrl = "https://einthusan.tv"
+
+// mirrorSet is the list of Einthusan base URLs, primary first. Every URL the
+// API builds, and every cache key, uses the primary; fetches go to whichever
+// mirror last answered properly, and fail over through the rest from there.
+type mirrorSet struct {
+	URLs    []string
+	healthy atomic.Int32 // index into urls of the mirror to try first
+}
+
+var Einthusan = NewMirrorSet(defaultBaseUrl)
+
+// NewMirrorSet returns the mirrors urls, primary first.
+func NewMirrorSet(urls ...string) *mirrorSet {
+	return &mirrorSet{URLs: urls}
+}
+
+// BaseURL is the primary mirror, without a trailing slash.
+func (m *mirrorSet) BaseURL() string {
+	return m.URLs[0]
+}
+
+// MirrorOf returns the mirror target is on, or "" if it is on none of them.
+func (m *mirrorSet) MirrorOf(target string) string {
+	for _, mirror := range m.URLs {
+		if strings.HasPrefix(target, mirror+"/") || target == mirror {
+			return mirror
+		}
+	}
+	return ""
+}
+
+// Candidates returns target rewritten onto each mirror, starting with the
+// one last marked healthy. URLs that aren't on the primary are returned as
+// they are.
+func (m *mirrorSet) Candidates(target string) []string {
+	path, ok := strings.CutPrefix(target, m.BaseURL())
+	if !ok {
+		return []string{target}
+	}
+	start := int(m.healthy.Load())
+	out := make([]string, 0, len(m.URLs))
+	for i := range m.URLs {
+		out = append(out, m.URLs[(start+i)%len(m.URLs)]+path)
+	}
+	return out
+}
+
+// MarkHealthy remembers the mirror that served target, so later fetches start
+// there instead of retrying a failing primary first every time.
+func (m *mirrorSet) MarkHealthy(target string) {
+	for i, mirror := range m.URLs {
+		if strings.HasPrefix(target, mirror+"/") || target == mirror {
+			if m.healthy.Swap(int32(i)) != int32(i) {
+				log.Printf("mirrors: switching to %s", mirror)
+			}
+			return
+		}
+	}
+}
+
+// ConfigureMirrors reads EINTHUSAN_BASE_URL: one base URL, or a
+// comma-separated list of mirrors to fail over through in order. An empty
+// value keeps the default.
+func ConfigureMirrors(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var urls []string
+	for _, part := range strings.Split(raw, ",") {
+		base := strings.TrimRight(strings.TrimSpace(part), "/")
+		if base == "" {
+			continue
+		}
+		u, err := url.Parse(base)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid EINTHUSAN_BASE_URL entry %q: must be an http(s) URL", part)
+		}
+		urls = append(urls, base)
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("invalid EINTHUSAN_BASE_URL %q: no URLs", raw)
+	}
+	Einthusan = NewMirrorSet(urls...)
+	return nil
+}
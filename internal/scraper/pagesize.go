@@ -0,0 +1,37 @@
+package scraper
+
+import "sync/atomic"
+
+// defaultPageSize stands in for PageSize until a full page has been parsed.
+// It is an estimate, not a measurement: testdata/results.html is
+// hand-written, and reports of live pages vary.
+const defaultPageSize = 20
+
+// fullPageSize is the longest results page with a successor parsed so far,
+// or 0 before the first.
+var fullPageSize atomic.Int64
+
+// notePageSize learns the page size from a parsed results page. Only a page
+// with a next link is known to be full; a degraded one may have lost movies
+// to the parser, and the largest wins for the same reason.
+func notePageSize(result Listing) {
+	if !result.HasNext || result.Degraded || len(result.Movies) == 0 {
+		return
+	}
+	for {
+		seen := fullPageSize.Load()
+		if int64(len(result.Movies)) <= seen || fullPageSize.CompareAndSwap(seen, int64(len(result.Movies))) {
+			return
+		}
+	}
+}
+
+// PageSize is how many movies Einthusan lists on a full results page, as
+// measured from the pages parsed so far (see notePageSize). A shorter page
+// is always the last one. Listing responses report it as page_size.
+func PageSize() int {
+	if size := fullPageSize.Load(); size > 0 {
+		return int(size)
+	}
+	return defaultPageSize
+}
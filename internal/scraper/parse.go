@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"apppp/internal/models"
+)
+
+// parseMovies reads the result entries from a listing page without touching
+// the network, using the configured selectors. It reports false when the
+// page has no results container at all, which is a different thing from a
+// listing with no results.
+func parseMovies(doc *goquery.Document) ([]models.MovieEntry, bool) {
+	summary := doc.Find(Selectors.Container)
+	if summary.Length() == 0 {
+		return nil, false
+	}
+	var movies []models.MovieEntry
+	listingItems(summary.First()).Each(func(i int, s *goquery.Selection) {
+		title, year := splitTitleYear(FirstText(s, Selectors.Title))
+		if title == "" {
+			return
+		}
+		if y := yearPattern.FindString(s.Find(Selectors.Year).Text()); y != "" {
+			year, _ = strconv.Atoi(y)
+		}
+		info := FirstText(s, Selectors.Info)
+		if y := yearPattern.FindString(info); year == 0 && y != "" {
+			year, _ = strconv.Atoi(y)
+		}
+		href := firstAttr(s, Selectors.Link, "href")
+		imgSrc := firstAttr(s, Selectors.Image, "src")
+		if strings.HasPrefix(imgSrc, "//") {
+			imgSrc = "https:" + imgSrc
+		}
+		movies = append(movies, models.MovieEntry{
+			ID: MovieID(href), ImgUrl: imgSrc, PageUrl: Einthusan.BaseURL() + href, Title: title, Year: year,
+			Duration: durationPattern.FindString(info),
+			Synopsis: FirstText(s, Selectors.Synopsis),
+			Views:    parseViews(s.Text()),
+		})
+	})
+	return movies, true
+}
+
+var viewsPattern = regexp.MustCompile(`(?i)(\d+(?:,\d{3})*(?:\.\d+)?)\s*([km])?\s*views?\b`)
+
+// parseViews reads a view count such as "12,345 views" or "1.2K views",
+// returning 0 when there is none.
+func parseViews(text string) int {
+	match := viewsPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToLower(match[2]) {
+	case "k":
+		n *= 1e3
+	case "m":
+		n *= 1e6
+	}
+	return int(n)
+}
+
+// FirstText returns the trimmed text of the first selector that yields any.
+func FirstText(s *goquery.Selection, selectors []string) string {
+	for _, selector := range selectors {
+		if text := strings.TrimSpace(s.Find(selector).First().Text()); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// firstAttr returns attr from the first selector whose element carries it.
+func firstAttr(s *goquery.Selection, selectors []string, attr string) string {
+	for _, selector := range selectors {
+		if value, ok := s.Find(selector).First().Attr(attr); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+var resultCountPattern = regexp.MustCompile(`(?i)(\d[\d,]*)\s+(?:results?|movies|matches)\b`)
+
+// ParseListing turns a fetched results page into a listing without touching
+// the network.
+func ParseListing(res *http.Response, doc *goquery.Document) (Listing, error) {
+	// An interstitial or error page parses fine but holds no results; report
+	// it rather than passing it off as an empty listing.
+	if err := DetectBlock(res, doc); err != nil {
+		return Listing{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return Listing{}, UnexpectedStatus("upstream", res)
+	}
+	movies, ok := parseMovies(doc)
+	if !ok {
+		return Listing{}, fmt.Errorf("%w: no results container", ErrUnexpectedPage)
+	}
+	result := Listing{Movies: movies, Total: parseResultCount(doc), HasNext: hasNextPage(doc), LastPage: parseLastPage(doc), Heading: parseHeading(doc), Degraded: listingDegraded(doc, len(movies))}
+	notePageSize(result)
+	return result, nil
+}
+
+var watchPathPattern = regexp.MustCompile(`/movie/watch/([^/?#]+)`)
+
+// MovieID extracts the ID from a /movie/watch/<id>/ link, with or without a
+// trailing slash or query string. It returns "" for any other link.
+func MovieID(href string) string {
+	match := watchPathPattern.FindStringSubmatch(href)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+var titleYearPattern = regexp.MustCompile(`\s*[(\[]((?:19|20)\d{2})[)\]]$`)
+
+// splitTitleYear separates a trailing "(2016)" from a title. It returns the
+// title unchanged and 0 when there is no such suffix.
+func splitTitleYear(title string) (string, int) {
+	match := titleYearPattern.FindStringSubmatchIndex(title)
+	if match == nil {
+		return title, 0
+	}
+	year, _ := strconv.Atoi(title[match[2]:match[3]])
+	return title[:match[0]], year
+}
+
+// hasNextPage reports whether the results pager links to a following page.
+// Einthusan shows a fixed number of movies per page, so this is the only
+// reliable way to tell the last page apart from a full one.
+func hasNextPage(doc *goquery.Document) bool {
+	next := false
+	doc.Find(".pagination a, #UIPagination a").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		rel, _ := s.Attr("rel")
+		class, _ := s.Attr("class")
+		text := strings.ToLower(strings.TrimSpace(s.Text()))
+		if _, disabled := s.Attr("disabled"); disabled || strings.Contains(class, "disabled") {
+			return true
+		}
+		next = rel == "next" || strings.Contains(class, "next") || text == "next" || text == "›" || text == "»"
+		return !next
+	})
+	return next
+}
+
+// parseLastPage returns the highest page number the pager shows, counting
+// the current page, which is usually rendered without a link.
+func parseLastPage(doc *goquery.Document) int {
+	last := 0
+	doc.Find(".pagination a, .pagination span, #UIPagination a, #UIPagination span").Each(func(i int, s *goquery.Selection) {
+		if n, err := strconv.Atoi(strings.TrimSpace(s.Text())); err == nil {
+			last = max(last, n)
+		}
+	})
+	return last
+}
+
+// parseHeading returns the results page's title heading, skipping headings
+// that are just a result count. It returns "" when there is none.
+func parseHeading(doc *goquery.Document) string {
+	heading := ""
+	doc.Find("#UIMovieFinder h1, #UIMovieFinder h2, .results-title, h1").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || resultCountPattern.MatchString(text) {
+			return true
+		}
+		heading = text
+		return false
+	})
+	return heading
+}
+
+// parseResultCount reads the "N results" style count from the page header,
+// returning 0 when there isn't one. h3 is skipped since movie titles use it.
+func parseResultCount(doc *goquery.Document) int {
+	total := 0
+	doc.Find("h1, h2, h4, .results, .result-count").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		match := resultCountPattern.FindStringSubmatch(s.Text())
+		if match == nil {
+			return true
+		}
+		total, _ = strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
+		return false
+	})
+	return total
+}
+
+var (
+	yearPattern     = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+	ratingPattern   = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	durationPattern = regexp.MustCompile(`(?i)\b\d+\s*h(?:rs?|ours?)?(?:\s*\d+\s*m(?:ins?|inutes?)?)?\b|\b\d+\s*min(?:s|utes)?\b`)
+)
@@ -0,0 +1,37 @@
+// Package scraper reads Einthusan: it fetches nothing itself, but knows the
+// mirrors, the selectors and how to turn a fetched page into listings and
+// movie details.
+package scraper
+
+import (
+	"context"
+	"slices"
+
+	"apppp/internal/models"
+)
+
+// Listing is one scraped results page.
+type Listing struct {
+	Movies   []models.MovieEntry
+	Total    int    // upstream's count of matches across all pages, 0 when not shown
+	LastPage int    // highest page number in the pager, 0 when there is none
+	HasNext  bool   // the pager links to a following page
+	Heading  string // the results heading, e.g. the actor's name on cast results
+	Degraded bool   // no movies parsed, though the page lists some; see listingDegraded
+}
+
+// Clone returns a copy of l whose movie slice can be sorted or filtered
+// without touching l.
+func (l Listing) Clone() Listing {
+	l.Movies = slices.Clone(l.Movies)
+	return l
+}
+
+// Scraper is the upstream the handlers read from. Handlers reach Einthusan
+// only through it, so tests can swap in a Scraper that serves parsed HTML
+// fixtures instead of fetching.
+type Scraper interface {
+	ScrapeListing(ctx context.Context, url string) (Listing, error)
+	ScrapeMovieDetail(ctx context.Context, language, id string) (*models.MovieDetail, error)
+	CheckAvailability(ctx context.Context, language, id string) (bool, error)
+}
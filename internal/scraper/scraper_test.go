@@ -0,0 +1,140 @@
+package scraper
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"apppp/internal/models"
+)
+
+// readFixture parses one of the saved pages in testdata.
+func readFixture(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func fixtureResponse(status int) *http.Response {
+	return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: http.Header{}}
+}
+
+func TestParseListing(t *testing.T) {
+	result, err := ParseListing(fixtureResponse(http.StatusOK), readFixture(t, "results.html"))
+	if err != nil {
+		t.Fatalf("parseListing: %v", err)
+	}
+	if len(result.Movies) != 20 {
+		t.Fatalf("parsed %d movies, want 20", len(result.Movies))
+	}
+	want := models.MovieEntry{
+		ID:       "A00x",
+		ImgUrl:   "https://img.einthusan.io/tamil/A00x.jpg",
+		PageUrl:  Einthusan.BaseURL() + "/movie/watch/A00x/?lang=tamil",
+		Title:    "Theri",
+		Year:     2010,
+		Duration: "2h 10m",
+		Synopsis: "Synopsis of Theri.",
+		Views:    1000,
+	}
+	if got := result.Movies[0]; got != want {
+		t.Errorf("first movie = %+v, want %+v", got, want)
+	}
+	if !result.HasNext || result.LastPage != 62 || result.Total != 1234 || result.Heading != "Recently Added" || result.Degraded {
+		t.Errorf("listing = {HasNext:%v LastPage:%d Total:%d Heading:%q Degraded:%v}, want {true 62 1234 \"Recently Added\" false}",
+			result.HasNext, result.LastPage, result.Total, result.Heading, result.Degraded)
+	}
+}
+
+func TestParseListingLastPage(t *testing.T) {
+	result, err := ParseListing(fixtureResponse(http.StatusOK), readFixture(t, "results_last.html"))
+	if err != nil {
+		t.Fatalf("parseListing: %v", err)
+	}
+	if len(result.Movies) != 7 || result.HasNext || result.LastPage != 62 {
+		t.Errorf("got %d movies, HasNext %v, LastPage %d; want 7, false, 62", len(result.Movies), result.HasNext, result.LastPage)
+	}
+}
+
+func TestParseListingUnexpectedPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		html    string
+		wantErr error
+	}{
+		{"no results container", http.StatusOK, `<html><body><p>Maintenance</p></body></html>`, ErrUnexpectedPage},
+		{"server error", http.StatusBadGateway, `<html><body>Bad gateway</body></html>`, ErrUnexpectedPage},
+		{"challenge", http.StatusOK, `<html><head><title>Just a moment...</title></head></html>`, ErrUpstreamBlocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ParseListing(fixtureResponse(tt.status), doc); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseMoviesDegraded(t *testing.T) {
+	// The results are still there, but under markup the selectors don't know.
+	html := `<div id="UIMovieSummary"><div class="grid"><a href="/movie/watch/A1/?lang=tamil">Theri</a></div></div>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ParseListing(fixtureResponse(http.StatusOK), doc)
+	if err != nil {
+		t.Fatalf("parseListing: %v", err)
+	}
+	if len(result.Movies) != 0 || !result.Degraded {
+		t.Errorf("got %d movies, Degraded %v; want 0, true", len(result.Movies), result.Degraded)
+	}
+}
+
+func TestPageSizeLearnedFromFullPages(t *testing.T) {
+	previous := fullPageSize.Load()
+	fullPageSize.Store(0)
+	t.Cleanup(func() { fullPageSize.Store(previous) })
+
+	page := func(n int, hasNext bool) Listing {
+		return Listing{Movies: make([]models.MovieEntry, n), HasNext: hasNext}
+	}
+	if PageSize() != defaultPageSize {
+		t.Fatalf("PageSize() = %d before any page, want the default %d", PageSize(), defaultPageSize)
+	}
+	notePageSize(page(7, false)) // a last page says nothing about the size
+	notePageSize(Listing{Movies: make([]models.MovieEntry, 5), HasNext: true, Degraded: true})
+	if PageSize() != defaultPageSize {
+		t.Errorf("PageSize() = %d after a last and a degraded page, want the default", PageSize())
+	}
+
+	// Full pages smaller than the default are measured as they are, and a
+	// larger one wins over them.
+	notePageSize(page(12, true))
+	if PageSize() != 12 {
+		t.Errorf("PageSize() = %d after a full page of 12, want 12", PageSize())
+	}
+	notePageSize(page(38, true))
+	notePageSize(page(12, true))
+	if PageSize() != 38 {
+		t.Errorf("PageSize() = %d after a full page of 38, want 38", PageSize())
+	}
+}
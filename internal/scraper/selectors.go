@@ -0,0 +1,115 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// selectorConfig holds the CSS selectors the scrapers read Einthusan pages
+// with. Each list is tried in order and the first selector that matches
+// wins, so a small markup change degrades to a looser match instead of
+// dropping every movie.
+type selectorConfig struct {
+	Container string   `json:"container"` // wraps a listing's results, and a watch page's summary
+	ItemPath  []string `json:"item_path"` // child steps from the container to each result
+	Title     []string `json:"title"`
+	Link      []string `json:"link"`
+	Image     []string `json:"image"`
+	Year      string   `json:"year"` // a result's year line, when it isn't in the title
+	Info      []string `json:"info"` // a result's year and runtime line
+	Synopsis  []string `json:"synopsis"`
+	ActorName []string `json:"actor_name"`
+	Trailer   []string `json:"trailer"`
+}
+
+// Selectors is what the scrapers use. It starts as the built-in set and can
+// be overridden without recompiling; see LoadSelectors.
+var Selectors = selectorConfig{
+	Container: "#UIMovieSummary",
+	ItemPath:  []string{"ul", "li"},
+	Title:     []string{"div.block2 > a.title > h3", "a.title h3", "a.title", "h3"},
+	Link:      []string{"div.block2 > a.title", "a.title", `a[href*="/movie/watch/"]`},
+	Image:     []string{"div.block1 > a > img", "div.block1 img", "img"},
+	Year:      "div.block2 > a.title > p",
+	Info:      []string{"div.block2 div.info", "div.info"},
+	Synopsis:  []string{"div.block2 p.synopsis", "p.synopsis"},
+	ActorName: []string{"#UICastProfile h2", "#UICastProfile h1", ".cast-name", "h1"},
+	Trailer:   []string{`a[href*="youtube.com/watch"]`, `a[href*="youtu.be/"]`, `iframe[src*="youtube.com/embed/"]`},
+}
+
+// LoadSelectors overrides the built-in selectors from SELECTORS_FILE and then
+// SELECTORS, each a JSON object with any of selectorConfig's fields, e.g.
+// {"title": ["div.info h3"]}. Fields left out keep their current value. Every
+// selector must parse, so a typo fails at startup rather than emptying every
+// listing.
+func LoadSelectors(inline, path string) error {
+	config := Selectors
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading SELECTORS_FILE: %w", err)
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("parsing SELECTORS_FILE %s: %w", path, err)
+		}
+	}
+	if inline != "" {
+		if err := json.Unmarshal([]byte(inline), &config); err != nil {
+			return fmt.Errorf("parsing SELECTORS: %w", err)
+		}
+	}
+	if err := config.validate(); err != nil {
+		return err
+	}
+	Selectors = config
+	return nil
+}
+
+func (sc selectorConfig) validate() error {
+	groups := map[string][]string{
+		"container":  {sc.Container},
+		"item_path":  sc.ItemPath,
+		"title":      sc.Title,
+		"link":       sc.Link,
+		"image":      sc.Image,
+		"year":       {sc.Year},
+		"info":       sc.Info,
+		"synopsis":   sc.Synopsis,
+		"actor_name": sc.ActorName,
+		"trailer":    sc.Trailer,
+	}
+	for field, list := range groups {
+		if len(list) == 0 {
+			return fmt.Errorf("invalid selectors: %s is empty", field)
+		}
+		for _, selector := range list {
+			if _, err := cascadia.ParseGroup(selector); err != nil {
+				return fmt.Errorf("invalid selectors: %s %q: %w", field, selector, err)
+			}
+		}
+	}
+	return nil
+}
+
+// listingItems returns the result entries under a listing's container.
+func listingItems(container *goquery.Selection) *goquery.Selection {
+	items := container
+	for _, step := range Selectors.ItemPath {
+		items = items.ChildrenFiltered(step)
+	}
+	return items
+}
+
+// listingDegraded reports whether a listing that parsed to no movies still
+// links to watch pages from inside its container. That means the selectors
+// no longer match the markup, not that the listing is empty.
+func listingDegraded(doc *goquery.Document, parsed int) bool {
+	if parsed > 0 {
+		return false
+	}
+	return doc.Find(Selectors.Container).First().Find(`a[href*="/movie/watch/"]`).Length() > 0
+}